@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// 审计日志固定的消息和字段名称, SIEM 可以按这些名称统一解析各服务的审计日志
+const (
+	// AuditMessage 审计日志的消息
+	AuditMessage = "audit"
+	// AuditKey 审计日志的标记字段, 值固定为 true, 用于从普通日志中筛选审计日志
+	AuditKey = "audit"
+	// AuditActorKey 执行操作的主体, 例如用户或服务账号
+	AuditActorKey = "actor"
+	// AuditActionKey 执行的操作, 例如 user.delete
+	AuditActionKey = "action"
+	// AuditTargetKey 操作的对象, 例如被删除的用户 ID
+	AuditTargetKey = "target"
+	// AuditResultKey 操作的结果, 例如 success、denied、failed
+	AuditResultKey = "result"
+)
+
+// Audit 以 Info 级别输出一条结构统一的审计日志, 请求 ID 和用户 ID 与 WithContext 一样从 ctx 中读取
+// 字段依次为 audit、actor、action、target、result, 之后是 fields; 普通日志实例可能开启采样, 审计日志建议使用 NewAudit 创建的实例
+func (l *Logger) Audit(ctx context.Context, actor, action, target, result string, fields ...zap.Field) {
+	l.WithContext(ctx).zap.Info(AuditMessage, auditFields(actor, action, target, result, fields)...)
+}
+
+// auditFields 返回审计日志的固定字段, 后面追加调用方的字段
+func auditFields(actor, action, target, result string, fields []zap.Field) []zap.Field {
+	return append([]zap.Field{
+		zap.Bool(AuditKey, true),
+		zap.String(AuditActorKey, actor),
+		zap.String(AuditActionKey, action),
+		zap.String(AuditTargetKey, target),
+		zap.String(AuditResultKey, result),
+	}, fields...)
+}
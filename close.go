@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// closers 日志实例打开的需要关闭的资源, 由同一实例派生出的所有实例共享
+type closers struct {
+	mu    sync.Mutex
+	once  sync.Once
+	funcs []func() error
+	err   error
+}
+
+// add 登记一个需要在 Close 时关闭的资源
+func (c *closers) add(fn func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.funcs = append(c.funcs, fn)
+}
+
+// close 按登记的相反顺序关闭所有资源, 只会执行一次
+func (c *closers) close() error {
+	c.once.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		var errs []error
+		for i := len(c.funcs) - 1; i >= 0; i-- {
+			if err := c.funcs[i](); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		c.err = errors.Join(errs...)
+	})
+	return c.err
+}
+
+// closeGuard 记录日志实例是否已经关闭, 由同一实例派生出的所有实例共享
+type closeGuard struct {
+	closed atomic.Bool
+	// dropped 关闭后被丢弃的日志条数
+	dropped atomic.Uint64
+}
+
+// closeGuardCore 日志实例关闭后丢弃所有日志, 避免写入已经关闭的文件
+// 关闭前已经通过 Check 的日志仍会尝试写入, 写入失败时由 zap 输出到 ErrorOutput
+type closeGuardCore struct {
+	zapcore.Core
+	guard *closeGuard
+}
+
+func (c *closeGuardCore) With(fields []zapcore.Field) zapcore.Core {
+	return &closeGuardCore{Core: c.Core.With(fields), guard: c.guard}
+}
+
+func (c *closeGuardCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.guard.closed.Load() {
+		c.guard.dropped.Add(1)
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *closeGuardCore) Sync() error {
+	if c.guard.closed.Load() {
+		return nil
+	}
+	return c.Core.Sync()
+}
+
+// LogAfterCloseTotal 返回 Close 之后被丢弃的日志条数, 不为0时说明关闭顺序有问题
+func (l *Logger) LogAfterCloseTotal() uint64 {
+	if l.closeGuard == nil {
+		return 0
+	}
+	return l.closeGuard.dropped.Load()
+}
+
+// addCloser 登记一个需要在 Close 时关闭的资源
+func (l *Logger) addCloser(fn func() error) {
+	if l.closers == nil {
+		l.closers = &closers{}
+	}
+	l.closers.add(fn)
+}
+
+// startPeriodicSync 在后台按 syncInterval 定期 Sync, Close 时停止
+// 标准输出的 Sync 错误没有意义, 这里全部忽略
+func (l *Logger) startPeriodicSync(core zapcore.Core) {
+	ticker := time.NewTicker(l.syncInterval)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				_ = core.Sync()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	l.addCloser(func() error {
+		ticker.Stop()
+		close(stop)
+		return nil
+	})
+}
+
+// Close 刷新缓冲并关闭日志文件等资源, 重复调用是安全的
+// 标准输出在很多平台上不支持 Sync, 因此这里忽略 Sync 的错误
+// 关闭后该实例及其派生实例的日志都会被丢弃, 丢弃的条数可以通过 LogAfterCloseTotal 查看
+func (l *Logger) Close() error {
+	_ = l.zap.Sync()
+	if l.closeGuard != nil {
+		l.closeGuard.closed.Store(true)
+	}
+	if l.closers == nil {
+		return nil
+	}
+	return l.closers.close()
+}
+
+// SyncContext 等待缓冲的日志写入完成, ctx 到期时返回 ctx 的错误, 同步输出时等同于 Sync
+func (l *Logger) SyncContext(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- l.zap.Sync()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown 在 ctx 的期限内刷新缓冲并关闭资源, 超时时返回 ctx 的错误, 关闭会在后台继续进行
+func (l *Logger) Shutdown(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
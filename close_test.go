@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogAfterClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.log")
+	var errOut bytes.Buffer
+	// 开发环境不轮转时不写入文件, 这里使用生产环境
+	l, err := New(
+		WithEnv(Production),
+		WithLogToFile(true),
+		WithRotate(false),
+		WithRotatePath(path),
+		WithErrorOutput(zapcore.AddSync(&errOut)),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	child := l.With()
+
+	l.Info("before close")
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	l.Info("after close")
+	child.Error("after close", errors.New("boom"))
+	if err := child.Sync(); err != nil {
+		t.Errorf("Sync() after Close error = %v", err)
+	}
+
+	if errOut.Len() > 0 {
+		t.Errorf("unexpected error output: %s", errOut.String())
+	}
+	if got := l.LogAfterCloseTotal(); got != 2 {
+		t.Errorf("LogAfterCloseTotal() = %d, want 2", got)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	entries, err := ParseEntries(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseEntries() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Message != "before close" {
+		t.Errorf("entries = %v, want only the entry before close", entries)
+	}
+}
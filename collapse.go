@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// collapseState 同一个日志实例派生出的所有 collapseCore 共享的状态, 只暂存最近的一条日志
+type collapseState struct {
+	mu      sync.Mutex
+	owner   *collapseCore
+	ent     zapcore.Entry
+	fields  []zapcore.Field
+	repeats int
+}
+
+// collapseCore 把连续重复的日志合并为一条, 附带 repeated 字段记录重复次数
+// 日志会暂存到下一条不同的日志到来或 Sync 时才写出, DPanic 及以上级别的日志总是立即写出
+type collapseCore struct {
+	zapcore.Core
+	state *collapseState
+}
+
+func newCollapseCore(core zapcore.Core) zapcore.Core {
+	return &collapseCore{Core: core, state: &collapseState{}}
+}
+
+func (c *collapseCore) With(fields []zapcore.Field) zapcore.Core {
+	return &collapseCore{Core: c.Core.With(fields), state: c.state}
+}
+
+func (c *collapseCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *collapseCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	s := c.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.owner == c && sameEntry(s.ent, ent) && sameFields(s.fields, fields) {
+		s.ent = ent
+		s.repeats++
+		return nil
+	}
+
+	err := s.flush()
+	if ent.Level >= zapcore.DPanicLevel {
+		return errors.Join(err, writeThrough(c.Core, ent, fields))
+	}
+	// 字段切片可能被调用方复用, 暂存时需要复制
+	s.owner, s.ent, s.fields, s.repeats = c, ent, append([]zapcore.Field(nil), fields...), 1
+	return err
+}
+
+func (c *collapseCore) Sync() error {
+	c.state.mu.Lock()
+	err := c.state.flush()
+	c.state.mu.Unlock()
+	return errors.Join(err, c.Core.Sync())
+}
+
+// flush 写出暂存的日志, 调用方需要持有锁
+func (s *collapseState) flush() error {
+	if s.owner == nil {
+		return nil
+	}
+	fields := s.fields
+	if s.repeats > 1 {
+		fields = appendFields(fields, zap.Int("repeated", s.repeats))
+	}
+	err := writeThrough(s.owner.Core, s.ent, fields)
+	s.owner, s.fields, s.repeats = nil, nil, 0
+	return err
+}
+
+// sameEntry 比较两条日志除时间以外的内容
+func sameEntry(a, b zapcore.Entry) bool {
+	return a.Level == b.Level &&
+		a.Message == b.Message &&
+		a.LoggerName == b.LoggerName &&
+		a.Caller == b.Caller &&
+		a.Stack == b.Stack
+}
+
+// sameFields 逐个比较字段, Stringer 字段的值可能不可比较, 使用 reflect.DeepEqual
+func sameFields(a, b []zapcore.Field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type == zapcore.StringerType && b[i].Type == zapcore.StringerType {
+			if a[i].Key != b[i].Key || !reflect.DeepEqual(a[i].Interface, b[i].Interface) {
+				return false
+			}
+			continue
+		}
+		if !a[i].Equals(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,553 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// onceKeys 记录 Once 已经输出过的 key
+var onceKeys sync.Map
+
+// deprecatedCounts 记录每个废弃特性已经输出的警告次数
+var deprecatedCounts sync.Map
+
+// entryHook 在每条日志写入前调用, 可以修改日志条目和字段, 返回 false 时丢弃该条日志
+type entryHook func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool)
+
+// entryCore 在写入前对每条日志执行 hook 的 core
+type entryCore struct {
+	zapcore.Core
+	hook entryHook
+}
+
+func newEntryCore(core zapcore.Core, hook entryHook) zapcore.Core {
+	return &entryCore{Core: core, hook: hook}
+}
+
+func (c *entryCore) With(fields []zapcore.Field) zapcore.Core {
+	return &entryCore{Core: c.Core.With(fields), hook: c.hook}
+}
+
+func (c *entryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *entryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent, fields, ok := c.hook(ent, fields)
+	if !ok {
+		return nil
+	}
+	return writeThrough(c.Core, ent, fields)
+}
+
+// writeThrough 在写入阶段才调用内部 core 的 Check, 保证 tee 中各个 core 仍按各自的级别过滤
+// CheckedEntry.Write 只把写入错误输出到 ErrorOutput, 这里捕获后返回, 由外层按 WithErrorOutput 的配置输出
+func writeThrough(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) error {
+	ce := core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+	capture := writeErrorPool.Get().(*writeErrorCapture)
+	capture.time = ent.Time
+	ce.ErrorOutput = capture
+	ce.Write(fields...)
+
+	err := capture.err
+	*capture = writeErrorCapture{}
+	writeErrorPool.Put(capture)
+	return err
+}
+
+// writeErrorPool 复用 writeErrorCapture, 避免每条日志分配
+var writeErrorPool = sync.Pool{
+	New: func() interface{} { return &writeErrorCapture{} },
+}
+
+// writeErrorCapture 作为内部 CheckedEntry 的 ErrorOutput, 把输出的写入错误还原为 error
+type writeErrorCapture struct {
+	time time.Time
+	err  error
+}
+
+func (c *writeErrorCapture) Write(p []byte) (int, error) {
+	// CheckedEntry 输出的格式为 "<时间> write error: <错误>\n"
+	msg := strings.TrimPrefix(string(p), c.time.String()+" write error: ")
+	c.err = errors.Join(c.err, errors.New(strings.TrimSuffix(msg, "\n")))
+	return len(p), nil
+}
+
+func (c *writeErrorCapture) Sync() error {
+	return nil
+}
+
+// appendFields 追加字段, 总是复制一份切片, 避免修改调用方传入的切片
+func appendFields(fields []zapcore.Field, extra ...zapcore.Field) []zapcore.Field {
+	return append(fields[:len(fields):len(fields)], extra...)
+}
+
+// levelCore 位于最外层的级别过滤 core, 替换 enab 即可调整子日志实例的级别
+type levelCore struct {
+	zapcore.Core
+	enab zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.enab.Enabled(lvl)
+}
+
+func (c *levelCore) Level() zapcore.Level {
+	return zapcore.LevelOf(c.enab)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enab: c.enab}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enab.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// wrapUnderLevel 把包装放在级别过滤之下, 保证 levelCore 始终位于最外层
+func wrapUnderLevel(core zapcore.Core, wrap func(zapcore.Core) zapcore.Core) zapcore.Core {
+	if lc, ok := core.(*levelCore); ok {
+		return &levelCore{Core: wrap(lc.Core), enab: lc.enab}
+	}
+	return wrap(core)
+}
+
+// atLevel 替换最外层 levelCore 的级别, 遇到 teeCore 时同时替换其中每个实例的级别
+func atLevel(core zapcore.Core, enab zapcore.LevelEnabler) zapcore.Core {
+	switch c := core.(type) {
+	case *levelCore:
+		return &levelCore{Core: atLevel(c.Core, enab), enab: enab}
+	case teeCore:
+		tee := make(teeCore, len(c))
+		for i, core := range c {
+			tee[i] = atLevel(core, enab)
+		}
+		return tee
+	}
+	return core
+}
+
+// teeCore 由 Tee 创建, 与 zapcore.NewTee 相同, 但 atLevel、setComponent 和 detachCore 能够深入其中的每个实例
+// teeCore 位于 levelCore 之下, 每个实例仍然由自己的 levelCore 按各自的级别过滤
+type teeCore []zapcore.Core
+
+func (c teeCore) Enabled(lvl zapcore.Level) bool {
+	for _, core := range c {
+		if core.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c teeCore) With(fields []zapcore.Field) zapcore.Core {
+	tee := make(teeCore, len(c))
+	for i, core := range c {
+		tee[i] = core.With(fields)
+	}
+	return tee
+}
+
+func (c teeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	for _, core := range c {
+		ce = core.Check(ent, ce)
+	}
+	return ce
+}
+
+func (c teeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var errs []error
+	for _, core := range c {
+		errs = append(errs, core.Write(ent, fields))
+	}
+	return errors.Join(errs...)
+}
+
+func (c teeCore) Sync() error {
+	var errs []error
+	for _, core := range c {
+		errs = append(errs, core.Sync())
+	}
+	return errors.Join(errs...)
+}
+
+// componentCore 在写入时添加 component 字段, 位于 wrapCore 的最外层, Component 替换组件名时不会产生重复的字段
+type componentCore struct {
+	zapcore.Core
+	name string
+}
+
+func (c *componentCore) With(fields []zapcore.Field) zapcore.Core {
+	return &componentCore{Core: c.Core.With(fields), name: c.name}
+}
+
+func (c *componentCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.name == "" {
+		return c.Core.Check(ent, ce)
+	}
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *componentCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeThrough(c.Core, ent, appendFields(fields, zap.String("component", c.name)))
+}
+
+// componentSetter 由 componentCore 及其外层包装实现, 返回替换了组件名的 core, 没有找到 componentCore 时返回 false
+type componentSetter interface {
+	withComponent(name string) (zapcore.Core, bool)
+}
+
+// setComponent 替换 core 中 componentCore 的组件名
+func setComponent(core zapcore.Core, name string) (zapcore.Core, bool) {
+	if s, ok := core.(componentSetter); ok {
+		return s.withComponent(name)
+	}
+	return core, false
+}
+
+func (c *componentCore) withComponent(name string) (zapcore.Core, bool) {
+	return &componentCore{Core: c.Core, name: name}, true
+}
+
+func (c *levelCore) withComponent(name string) (zapcore.Core, bool) {
+	core, ok := setComponent(c.Core, name)
+	if !ok {
+		return c, false
+	}
+	return &levelCore{Core: core, enab: c.enab}, true
+}
+
+func (c teeCore) withComponent(name string) (zapcore.Core, bool) {
+	tee := make(teeCore, len(c))
+	for i, core := range c {
+		var ok bool
+		// 找不到 componentCore 的实例与 Component 相同, 退回为添加字段
+		if tee[i], ok = setComponent(core, name); !ok {
+			tee[i] = core.With([]zapcore.Field{zap.String("component", name)})
+		}
+	}
+	return tee, true
+}
+
+func (c *entryCore) withComponent(name string) (zapcore.Core, bool) {
+	core, ok := setComponent(c.Core, name)
+	if !ok {
+		return c, false
+	}
+	return &entryCore{Core: core, hook: c.hook}, true
+}
+
+func (c *lazyContextCore) withComponent(name string) (zapcore.Core, bool) {
+	core, ok := setComponent(c.Core, name)
+	if !ok {
+		return c, false
+	}
+	return &lazyContextCore{Core: core, ctx: c.ctx, keys: c.keys}, true
+}
+
+// fieldHook 在字段编码前逐个调用, 可以替换字段, 返回 false 时丢弃该字段
+type fieldHook func(field zapcore.Field) (zapcore.Field, bool)
+
+// fieldCore 对 With 和每条日志的字段执行 hook 的 core
+type fieldCore struct {
+	zapcore.Core
+	hook fieldHook
+}
+
+func newFieldCore(core zapcore.Core, hook fieldHook) zapcore.Core {
+	return &fieldCore{Core: core, hook: hook}
+}
+
+func (c *fieldCore) With(fields []zapcore.Field) zapcore.Core {
+	return &fieldCore{Core: c.Core.With(c.mapFields(fields)), hook: c.hook}
+}
+
+func (c *fieldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *fieldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeThrough(c.Core, ent, c.mapFields(fields))
+}
+
+// mapFields 返回处理后的新切片, 不修改传入的切片
+func (c *fieldCore) mapFields(fields []zapcore.Field) []zapcore.Field {
+	mapped := make([]zapcore.Field, 0, len(fields))
+	for _, field := range fields {
+		if field, ok := c.hook(field); ok {
+			mapped = append(mapped, field)
+		}
+	}
+	return mapped
+}
+
+// fieldValue 取出字段的值, 与编码器看到的值一致
+func fieldValue(field zapcore.Field) interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	field.AddTo(enc)
+	return enc.Fields[field.Key]
+}
+
+// lazyContextCore 在每条日志写入时重新从上下文读取指定键的值
+type lazyContextCore struct {
+	zapcore.Core
+	ctx  context.Context
+	keys []string
+}
+
+func (c *lazyContextCore) With(fields []zapcore.Field) zapcore.Core {
+	return &lazyContextCore{Core: c.Core.With(fields), ctx: c.ctx, keys: c.keys}
+}
+
+func (c *lazyContextCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *lazyContextCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeThrough(c.Core, ent, appendFields(fields, c.values()...))
+}
+
+// values 返回上下文中指定键的当前值
+func (c *lazyContextCore) values() []zapcore.Field {
+	var fields []zapcore.Field
+	for _, key := range c.keys {
+		if val := c.ctx.Value(key); val != nil {
+			fields = append(fields, zap.Any(key, val))
+		}
+	}
+	return fields
+}
+
+// detacher 由引用了上下文的 core 及其外层包装实现, 返回不再引用上下文的 core
+type detacher interface {
+	detach() zapcore.Core
+}
+
+// detachCore 让 core 不再引用上下文, 不引用上下文的 core 原样返回
+func detachCore(core zapcore.Core) zapcore.Core {
+	if d, ok := core.(detacher); ok {
+		return d.detach()
+	}
+	return core
+}
+
+// detach 把上下文中的值固定为字段, 之后不再读取上下文
+func (c *lazyContextCore) detach() zapcore.Core {
+	core := detachCore(c.Core)
+	if fields := c.values(); len(fields) > 0 {
+		core = core.With(fields)
+	}
+	return core
+}
+
+func (c *levelCore) detach() zapcore.Core {
+	return &levelCore{Core: detachCore(c.Core), enab: c.enab}
+}
+
+func (c teeCore) detach() zapcore.Core {
+	tee := make(teeCore, len(c))
+	for i, core := range c {
+		tee[i] = detachCore(core)
+	}
+	return tee
+}
+
+func (c *entryCore) detach() zapcore.Core {
+	return &entryCore{Core: detachCore(c.Core), hook: c.hook}
+}
+
+// schemaCore 检查每条日志是否包含必需字段的 core, 缺少时额外输出一条 Warn 日志
+type schemaCore struct {
+	zapcore.Core
+	required []string
+	// seen 记录通过 With 已经添加的必需字段, 与 required 一一对应
+	seen []bool
+}
+
+func newSchemaCore(core zapcore.Core, required []string) zapcore.Core {
+	return &schemaCore{Core: core, required: required, seen: make([]bool, len(required))}
+}
+
+func (c *schemaCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &schemaCore{
+		Core:     c.Core.With(fields),
+		required: c.required,
+		seen:     append([]bool(nil), c.seen...),
+	}
+	for i, key := range c.required {
+		if !clone.seen[i] && hasField(fields, key) {
+			clone.seen[i] = true
+		}
+	}
+	return clone
+}
+
+func (c *schemaCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *schemaCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var missing []string
+	for i, key := range c.required {
+		if !c.seen[i] && !hasField(fields, key) {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) > 0 {
+		warn := zapcore.Entry{
+			Level:   zapcore.WarnLevel,
+			Time:    ent.Time,
+			Caller:  ent.Caller,
+			Message: "Log entry is missing required fields",
+		}
+		_ = writeThrough(c.Core, warn, []zapcore.Field{
+			zap.Strings("missing_fields", missing),
+			zap.String("entry_message", ent.Message),
+		})
+	}
+	return writeThrough(c.Core, ent, fields)
+}
+
+// hasField 检查字段中是否包含指定的键
+func hasField(fields []zapcore.Field, key string) bool {
+	for i := range fields {
+		if fields[i].Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeCore 保存 With 的字段而不立即编码, 写入时与日志字段合并, 同名字段只保留最后的值
+type dedupeCore struct {
+	zapcore.Core
+	fields []zapcore.Field
+}
+
+func (c *dedupeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupeCore{Core: c.Core, fields: mergeFields(c.fields, fields)}
+}
+
+func (c *dedupeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dedupeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeThrough(c.Core, ent, mergeFields(c.fields, fields))
+}
+
+// mergeFields 返回合并后的新切片, extra 中的字段覆盖 base 中的同名字段并保留原来的位置
+// 出现 Namespace 之后的字段属于嵌套对象, 不再参与去重
+func mergeFields(base, extra []zapcore.Field) []zapcore.Field {
+	merged := make([]zapcore.Field, 0, len(base)+len(extra))
+	merged = append(merged, base...)
+	nested := hasNamespace(merged)
+	for _, field := range extra {
+		if !nested {
+			if field.Type == zapcore.NamespaceType {
+				nested = true
+			} else if i := fieldIndex(merged, field.Key); i >= 0 {
+				merged[i] = field
+				continue
+			}
+		}
+		merged = append(merged, field)
+	}
+	return merged
+}
+
+// fieldIndex 返回指定键的字段位置, 不存在时返回 -1
+func fieldIndex(fields []zapcore.Field, key string) int {
+	for i := range fields {
+		if fields[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasNamespace 检查字段中是否包含 Namespace
+func hasNamespace(fields []zapcore.Field) bool {
+	for i := range fields {
+		if fields[i].Type == zapcore.NamespaceType {
+			return true
+		}
+	}
+	return false
+}
+
+// indentWriter 把每条 JSON 日志缩进后写入, 不是合法 JSON 的内容原样写入
+type indentWriter struct {
+	zapcore.WriteSyncer
+}
+
+func (w *indentWriter) Write(p []byte) (int, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimRight(p, "\r\n"), "", "  "); err != nil {
+		return w.WriteSyncer.Write(p)
+	}
+	buf.WriteByte('\n')
+	if _, err := w.WriteSyncer.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// countingWriter 统计写入字节数的 WriteSyncer
+type countingWriter struct {
+	zapcore.WriteSyncer
+	n *atomic.Uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	w.n.Add(uint64(n))
+	return n, err
+}
+
+// fsyncWriter 每次写入后立即 Sync 的 WriteSyncer, 写入文件时会调用 fsync
+type fsyncWriter struct {
+	zapcore.WriteSyncer
+}
+
+func (w *fsyncWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, w.WriteSyncer.Sync()
+}
@@ -0,0 +1,29 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestDedupeFieldsConflictingKey(t *testing.T) {
+	l, buf := newTestLogger(t, WithDedupeFields(true))
+	ctx := context.WithValue(context.Background(), RequestKey, "from-ctx")
+
+	l.With(zap.String(RequestKey, "from-with")).InfoCtx(ctx, "hello")
+
+	// ParseEntries 解析为 map 时会掩盖重复的键, 这里直接检查原始输出
+	if n := strings.Count(buf.String(), `"`+RequestKey+`"`); n != 1 {
+		t.Fatalf("%s appears %d times, want 1: %s", RequestKey, n, buf.String())
+	}
+	entries := parseTestEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	// 上下文字段在 With 之后添加, 是最后设置的值
+	if got := entries[0].Fields[RequestKey]; got != "from-ctx" {
+		t.Errorf("%s = %v, want from-ctx", RequestKey, got)
+	}
+}
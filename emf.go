@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// EMF 按 CloudWatch Embedded Metric Format 输出一条日志, CloudWatch 会从中提取指标
+func (l *Logger) EMF(namespace string, metrics map[string]float64, dims map[string]string) {
+	metricNames := sortedKeys(metrics)
+	dimNames := sortedKeys(dims)
+
+	fields := make([]zap.Field, 0, len(metrics)+len(dims)+1)
+	fields = append(fields, zap.Object("_aws", emfMetadata{
+		timestamp: time.Now(),
+		namespace: namespace,
+		metrics:   metricNames,
+		dims:      dimNames,
+	}))
+	for _, name := range dimNames {
+		fields = append(fields, zap.String(name, dims[name]))
+	}
+	for _, name := range metricNames {
+		fields = append(fields, zap.Float64(name, metrics[name]))
+	}
+
+	l.zap.Info("EMF", fields...)
+}
+
+// emfMetadata EMF 的 _aws 元数据
+type emfMetadata struct {
+	timestamp time.Time
+	namespace string
+	metrics   []string
+	dims      []string
+}
+
+func (m emfMetadata) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt64("Timestamp", m.timestamp.UnixMilli())
+	return enc.AddArray("CloudWatchMetrics", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		return arr.AppendObject(zapcore.ObjectMarshalerFunc(func(directive zapcore.ObjectEncoder) error {
+			directive.AddString("Namespace", m.namespace)
+			err := directive.AddArray("Dimensions", zapcore.ArrayMarshalerFunc(func(sets zapcore.ArrayEncoder) error {
+				return sets.AppendArray(zapcore.ArrayMarshalerFunc(func(set zapcore.ArrayEncoder) error {
+					for _, name := range m.dims {
+						set.AppendString(name)
+					}
+					return nil
+				}))
+			}))
+			if err != nil {
+				return err
+			}
+			return directive.AddArray("Metrics", zapcore.ArrayMarshalerFunc(func(metrics zapcore.ArrayEncoder) error {
+				for _, name := range m.metrics {
+					err := metrics.AppendObject(zapcore.ObjectMarshalerFunc(func(metric zapcore.ObjectEncoder) error {
+						metric.AddString("Name", name)
+						return nil
+					}))
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			}))
+		}))
+	}))
+}
+
+// sortedKeys 返回排好序的 map 键, 保证输出稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry 解析后的一条 JSON 日志, 字段名与默认的编码配置一致
+type Entry struct {
+	// Timestamp 日志时间, 对应 time 字段
+	Timestamp time.Time
+	// Level 日志级别, 对应 level 字段
+	Level zapcore.Level
+	// Message 日志消息, 对应 message 字段
+	Message string
+	// Caller 调用位置, 对应 caller 字段
+	Caller string
+	// Stacktrace 堆栈, 对应 stacktrace 字段
+	Stacktrace string
+	// Fields 其余的字段, 数字以 json.Number 保存
+	Fields map[string]interface{}
+}
+
+// entryTimeLayouts 解析 time 字段时依次尝试的格式
+// 小数部分使用 .999999999, 可以解析 WithTimePrecision 设置的任意位数, 也可以解析没有小数部分的时间;
+// TimeLayoutRFC3339Z 的各种精度都可以由 time.RFC3339Nano 解析
+var entryTimeLayouts = []string{"2006-01-02 15:04:05.999999999Z0700", time.RFC3339Nano}
+
+// ParseEntries 从 r 中依次解析本库输出的 JSON 日志, 例如测试中读取子进程的标准输出
+func ParseEntries(r io.Reader) ([]Entry, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var entries []Entry
+	for {
+		var raw map[string]interface{}
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return entries, err
+		}
+
+		entry, err := parseEntry(raw)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// parseEntry 把解码后的 JSON 对象转换为 Entry
+func parseEntry(raw map[string]interface{}) (Entry, error) {
+	entry := Entry{Fields: raw}
+
+	if v, ok := raw["time"].(string); ok {
+		t, err := parseEntryTime(v)
+		if err != nil {
+			return entry, err
+		}
+		entry.Timestamp = t
+		delete(raw, "time")
+	}
+	if v, ok := raw["level"].(string); ok {
+		level, err := ParseLevel(v)
+		if err != nil {
+			return entry, err
+		}
+		entry.Level = level
+		delete(raw, "level")
+	}
+	if v, ok := raw["message"].(string); ok {
+		entry.Message = v
+		delete(raw, "message")
+	}
+	if v, ok := raw["caller"].(string); ok {
+		entry.Caller = v
+		delete(raw, "caller")
+	}
+	if v, ok := raw["stacktrace"].(string); ok {
+		entry.Stacktrace = v
+		delete(raw, "stacktrace")
+	}
+
+	return entry, nil
+}
+
+// parseEntryTime 按支持的格式解析时间
+func parseEntryTime(v string) (time.Time, error) {
+	for _, layout := range entryTimeLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid log time %q", v)
+}
+
+// String 读取字符串字段, 字段不存在或类型不符时 ok 为 false
+func (e Entry) String(key string) (string, bool) {
+	v, ok := e.Fields[key].(string)
+	return v, ok
+}
+
+// Int 读取整数字段, 字段不存在或不是整数时 ok 为 false
+func (e Entry) Int(key string) (int64, bool) {
+	n, ok := e.Fields[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	v, err := n.Int64()
+	return v, err == nil
+}
+
+// Float 读取数值字段, 字段不存在或不是数值时 ok 为 false
+func (e Entry) Float(key string) (float64, bool) {
+	n, ok := e.Fields[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	v, err := n.Float64()
+	return v, err == nil
+}
+
+// Bool 读取布尔字段, 字段不存在或类型不符时 ok 为 false
+func (e Entry) Bool(key string) (bool, bool) {
+	v, ok := e.Fields[key].(bool)
+	return v, ok
+}
+
+// Time 读取时间字段, 支持与 time 字段相同的格式, 字段不存在或无法解析时 ok 为 false
+func (e Entry) Time(key string) (time.Time, bool) {
+	v, ok := e.Fields[key].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := parseEntryTime(v)
+	return t, err == nil
+}
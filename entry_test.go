@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEntriesTimePrecision(t *testing.T) {
+	tests := []struct {
+		name      string
+		precision time.Duration
+		rfc3339Z  bool
+	}{
+		{name: "second", precision: time.Second},
+		{name: "millisecond", precision: time.Millisecond},
+		{name: "microsecond", precision: time.Microsecond},
+		{name: "nanosecond", precision: time.Nanosecond},
+		{name: "rfc3339 second", precision: time.Second, rfc3339Z: true},
+		{name: "rfc3339 microsecond", precision: time.Microsecond, rfc3339Z: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newTestLogger(t, WithTimeLayoutUTCZ(tt.rfc3339Z), WithTimePrecision(tt.precision))
+			before := time.Now().Truncate(tt.precision)
+			l.Info("hello")
+
+			entries := parseTestEntries(t, buf)
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			if got := entries[0].Timestamp; got.Before(before) || got.After(time.Now()) {
+				t.Errorf("Timestamp = %v, want between %v and now", got, before)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package logger
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// newEventLogCore 非 Windows 平台不支持事件日志
+func (l *Logger) newEventLogCore(enc zapcore.Encoder, enab zapcore.LevelEnabler) (zapcore.Core, error) {
+	return nil, errors.New("windows event log is only supported on windows")
+}
@@ -0,0 +1,79 @@
+//go:build windows
+
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// eventID 写入事件日志时使用的事件ID
+const eventID = 1
+
+// eventLogCore 把日志写入 Windows 事件日志的 core
+type eventLogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	log *eventlog.Log
+}
+
+// newEventLogCore 打开事件源并创建事件日志 core, 只有 Warn 及以上级别会写入事件日志
+// 事件源需要预先注册, 例如在安装时调用 eventlog.InstallAsEventCreate
+func (l *Logger) newEventLogCore(enc zapcore.Encoder, enab zapcore.LevelEnabler) (zapcore.Core, error) {
+	log, err := eventlog.Open(l.eventLogSource)
+	if err != nil {
+		return nil, err
+	}
+	l.addCloser(log.Close)
+
+	return &eventLogCore{
+		LevelEnabler: zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+			return lvl >= zapcore.WarnLevel && enab.Enabled(lvl)
+		}),
+		enc: enc,
+		log: log,
+	}, nil
+}
+
+func (c *eventLogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &eventLogCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          c.enc.Clone(),
+		log:          c.log,
+	}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *eventLogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *eventLogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := buf.String()
+	buf.Free()
+
+	// 把 zap 的级别映射到事件日志的类型
+	switch {
+	case ent.Level >= zapcore.ErrorLevel:
+		return c.log.Error(eventID, msg)
+	case ent.Level == zapcore.WarnLevel:
+		return c.log.Warning(eventID, msg)
+	default:
+		return c.log.Info(eventID, msg)
+	}
+}
+
+func (c *eventLogCore) Sync() error {
+	return nil
+}
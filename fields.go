@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"encoding/hex"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// timeType time.Time 的反射类型, 时间按普通值记录而不是展开为嵌套对象
+var timeType = reflect.TypeOf(time.Time{})
+
+// HexField 以十六进制字符串记录二进制数据, 适合哈希值和二进制标识, zap.Binary 使用的是 base64
+func HexField(key string, val []byte) zap.Field {
+	return zap.String(key, hex.EncodeToString(val))
+}
+
+// Array 把实现了 zapcore.ObjectMarshaler 的元素记录为 JSON 数组, 元素在写入时才编码, 被级别过滤的日志没有编码开销
+func Array(key string, items []zapcore.ObjectMarshaler) zap.Field {
+	return zap.Array(key, objectArray(items))
+}
+
+// AnySlice 把任意类型的切片记录为 JSON 数组, 实现了 zapcore.ObjectMarshaler 的元素按对象编码, 其余通过反射编码
+// 与 Array 一样在写入时才编码, 不需要先把切片转换为 []zapcore.ObjectMarshaler
+func AnySlice[T any](key string, items []T) zap.Field {
+	return zap.Array(key, anyArray[T](items))
+}
+
+// objectArray 按对象编码每个元素的数组
+type objectArray []zapcore.ObjectMarshaler
+
+func (a objectArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, item := range a {
+		if err := enc.AppendObject(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// anyArray 按元素类型选择编码方式的数组
+type anyArray[T any] []T
+
+func (a anyArray[T]) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, item := range a {
+		if m, ok := interface{}(item).(zapcore.ObjectMarshaler); ok {
+			if err := enc.AppendObject(m); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := enc.AppendReflected(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Secret 在调用处标记敏感值, 无论是否配置了脱敏都输出 ***, 真实的值不会进入字段, 也不会写入输出缓冲
+func Secret(key, value string) zap.Field {
+	return zap.String(key, "***")
+}
+
+// hexBinaryField 把 zap.Binary 创建的字段转换为十六进制字符串
+func hexBinaryField(field zapcore.Field) (zapcore.Field, bool) {
+	if field.Type != zapcore.BinaryType {
+		return field, true
+	}
+	if val, ok := field.Interface.([]byte); ok {
+		return HexField(field.Key, val), true
+	}
+	return field, true
+}
+
+// StructFields 把结构体的导出字段转换为日志字段, 字段名取自 log 标签, 没有标签时使用字段名
+// 标签为 log:"-" 或带有 sensitive 选项（例如 log:"password,sensitive"）的字段会被跳过, 嵌套的结构体记录为嵌套对象
+// 嵌套超过5层的结构体输出为 [max depth exceeded], 循环引用输出为 [cycle]; v 不是结构体或结构体指针时返回 nil
+func StructFields(v interface{}) []zap.Field {
+	rv := reflect.ValueOf(v)
+	var ptrs []uintptr
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		ptrs = append(ptrs, rv.Pointer())
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	// 字段指回顶层结构体时同样输出 cycleMarker
+	root := structObject{v: rv, path: ptrs, depth: 1}
+	var fields []zap.Field
+	forEachStructField(rv, func(name string, fv reflect.Value) {
+		fields = append(fields, root.field(name, fv))
+	})
+	return fields
+}
+
+// forEachStructField 遍历需要记录的结构体字段
+func forEachStructField(rv reflect.Value, fn func(name string, fv reflect.Value)) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup("log"); ok {
+			if tag == "-" {
+				continue
+			}
+			tagName, opts, _ := strings.Cut(tag, ",")
+			if hasTagOption(opts, "sensitive") {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		fn(name, rv.Field(i))
+	}
+}
+
+// hasTagOption 检查标签选项中是否包含 option
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// structObject 按 StructFields 的规则编码嵌套的结构体
+type structObject struct {
+	v reflect.Value
+	// path 从顶层到当前结构体经过的指针, 用于发现循环引用
+	path []uintptr
+	// depth 结构体的嵌套深度, 顶层为1
+	depth int
+}
+
+// field 把结构体的单个字段转换为日志字段, 结构体和结构体指针转换为嵌套对象
+func (o structObject) field(name string, fv reflect.Value) zap.Field {
+	sv := fv
+	path := o.path[:len(o.path):len(o.path)]
+	for sv.Kind() == reflect.Pointer && !sv.IsNil() {
+		if slices.Contains(path, sv.Pointer()) {
+			return zap.String(name, cycleMarker)
+		}
+		path = append(path, sv.Pointer())
+		sv = sv.Elem()
+	}
+	if sv.Kind() == reflect.Struct && sv.Type() != timeType {
+		if o.depth >= defaultMaxReflectDepth {
+			return zap.String(name, maxDepthMarker)
+		}
+		return zap.Object(name, structObject{v: sv, path: path, depth: o.depth + 1})
+	}
+	if !fv.CanInterface() {
+		return zap.Skip()
+	}
+	return zap.Any(name, fv.Interface())
+}
+
+func (o structObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	forEachStructField(o.v, func(name string, fv reflect.Value) {
+		o.field(name, fv).AddTo(enc)
+	})
+	return nil
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type structFieldsNode struct {
+	Name string
+	Next *structFieldsNode
+}
+
+type structFieldsDeep struct {
+	Child *structFieldsDeep
+}
+
+func TestStructFieldsCycle(t *testing.T) {
+	n := &structFieldsNode{Name: "a"}
+	n.Next = &structFieldsNode{Name: "b", Next: n}
+
+	l, buf := newTestLogger(t)
+	l.Info("node", zap.Object("node", zapFieldsObject(StructFields(n))))
+
+	entries := parseTestEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	node, _ := entries[0].Fields["node"].(map[string]interface{})
+	next, _ := node["Next"].(map[string]interface{})
+	if next["Name"] != "b" || next["Next"] != cycleMarker {
+		t.Errorf("node = %v, want Next.Next = %s", node, cycleMarker)
+	}
+}
+
+func TestStructFieldsMaxDepth(t *testing.T) {
+	root := &structFieldsDeep{}
+	d := root
+	for i := 0; i < 10; i++ {
+		d.Child = &structFieldsDeep{}
+		d = d.Child
+	}
+
+	l, buf := newTestLogger(t)
+	l.Info("deep", StructFields(root)...)
+
+	entries := parseTestEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	var v interface{} = entries[0].Fields["Child"]
+	depth := 1
+	for {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			break
+		}
+		v = m["Child"]
+		depth++
+	}
+	if v != maxDepthMarker || depth != defaultMaxReflectDepth {
+		t.Errorf("got %v at depth %d, want %s at depth %d", v, depth, maxDepthMarker, defaultMaxReflectDepth)
+	}
+}
+
+// zapFieldsObject 把多个字段包装为一个嵌套对象
+type zapFieldsObject []zap.Field
+
+func (f zapFieldsObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range f {
+		field.AddTo(enc)
+	}
+	return nil
+}
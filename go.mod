@@ -5,6 +5,8 @@ go 1.24.2
 require (
 	github.com/natefinch/lumberjack v2.0.0+incompatible
 	go.uber.org/zap v1.27.1
+	golang.org/x/sys v0.36.0
+	google.golang.org/protobuf v1.36.12
 )
 
 require (
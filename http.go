@@ -0,0 +1,452 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultDebugHeader 默认开启单个请求调试日志的请求头
+const defaultDebugHeader = "X-Debug"
+
+// sensitiveHeaders 记录请求头时需要脱敏的请求头
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+}
+
+// sensitiveBodyKeys WithBodyLogging 记录请求和响应内容时默认脱敏的 JSON 键和表单字段, 不区分大小写
+var sensitiveBodyKeys = []string{
+	"password", "passwd", "secret", "client_secret",
+	"token", "access_token", "refresh_token", "id_token",
+	"api_key", "apikey", "authorization",
+}
+
+// debugContextKey 上下文中标记开启调试日志的键
+type debugContextKey struct{}
+
+// ContextWithDebug 标记上下文开启调试日志, ForContext/ForRequest 会返回 Debug 级别的日志实例
+func ContextWithDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugContextKey{}, true)
+}
+
+// ForContext 返回附带上下文字段的日志实例, 上下文标记了调试时级别提升为 Debug
+func (l *Logger) ForContext(ctx context.Context) *Logger {
+	newLogger := l.WithContext(ctx)
+	if debug, _ := ctx.Value(debugContextKey{}).(bool); debug {
+		newLogger = newLogger.AtLevel(zapcore.DebugLevel)
+	}
+	return newLogger
+}
+
+// ForRequest 返回附带请求上下文字段的日志实例, 请求头或上下文标记了调试时级别提升为 Debug
+func (l *Logger) ForRequest(r *http.Request) *Logger {
+	newLogger := l.ForContext(r.Context())
+
+	header := l.debugHeader
+	if header == "" {
+		header = defaultDebugHeader
+	}
+	if debug, _ := strconv.ParseBool(r.Header.Get(header)); debug {
+		newLogger = newLogger.AtLevel(zapcore.DebugLevel)
+	}
+	return newLogger
+}
+
+// LogHTTPRequest 输出统一格式的访问日志, 5xx 使用 Error 级别, 4xx 使用 Warn 级别, 其余使用 Info 级别
+func (l *Logger) LogHTTPRequest(ctx context.Context, r *http.Request, status int, size int64, dur time.Duration) {
+	ce := l.WithContext(ctx).zap.Check(httpStatusLevel(status), "HTTP request")
+	if ce == nil {
+		return
+	}
+	ce.Write(httpRequestFields(r, status, size, dur)...)
+}
+
+// httpStatusLevel 按状态码选择访问日志的级别
+func httpStatusLevel(status int) zapcore.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return zapcore.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// httpRequestFields 返回访问日志的字段
+func httpRequestFields(r *http.Request, status int, size int64, dur time.Duration) []zap.Field {
+	return []zap.Field{
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.Int("status", status),
+		zap.Int64("bytes", size),
+		zap.Float64("duration_ms", float64(dur)/float64(time.Millisecond)),
+		zap.String("remote_ip", remoteIP(r)),
+		zap.String("user_agent", r.UserAgent()),
+	}
+}
+
+// HTTPOption 配置 HTTPMiddleware 的选项
+type HTTPOption func(*httpOptions)
+
+// httpOptions HTTPMiddleware 的配置
+type httpOptions struct {
+	// bodyBytes 记录请求和响应内容的最大字节数, 为 0 时不记录
+	bodyBytes int
+	// sensitiveKeys 除默认的键以外需要脱敏的 JSON 键和表单字段
+	sensitiveKeys []string
+	// redactBody 自定义的内容脱敏函数, 在默认的脱敏之后执行
+	redactBody func(contentType, body string) string
+}
+
+// WithBodyLogging 在访问日志中记录请求和响应的内容, 每个最多 maxBytes 字节, 超出部分截断
+// 只记录文本、JSON、XML 和表单等类型的内容, 二进制内容不记录; 内容作为 request_body 和 response_body 字段;
+// 默认把 password、token、secret、api_key 等 JSON 键和表单字段的值替换为 ***, 可以通过 WithSensitiveBodyKeys 添加,
+// 其他格式的内容可以通过 WithBodyRedactor 脱敏; 内容是在转发时顺带记录的, 不影响流式响应, 只建议在调试时开启
+func WithBodyLogging(maxBytes int) HTTPOption {
+	return func(o *httpOptions) {
+		o.bodyBytes = maxBytes
+	}
+}
+
+// WithSensitiveBodyKeys 添加记录请求和响应内容时需要脱敏的 JSON 键和表单字段, 不区分大小写
+func WithSensitiveBodyKeys(keys ...string) HTTPOption {
+	return func(o *httpOptions) {
+		o.sensitiveKeys = append(o.sensitiveKeys, keys...)
+	}
+}
+
+// WithBodyRedactor 设置自定义的内容脱敏函数, 在默认的脱敏之后执行, 返回记录到日志中的内容
+// contentType 为请求或响应的 Content-Type, 内容可能已被截断, 不一定是完整的 JSON 或 XML
+func WithBodyRedactor(redactor func(contentType, body string) string) HTTPOption {
+	return func(o *httpOptions) {
+		o.redactBody = redactor
+	}
+}
+
+// bodyRedactor 脱敏记录的请求和响应内容
+type bodyRedactor struct {
+	// jsonPattern 匹配敏感的 JSON 键及其值, 值可能因截断而不完整
+	jsonPattern *regexp.Regexp
+	// formPattern 匹配敏感的表单字段及其值
+	formPattern *regexp.Regexp
+	custom      func(contentType, body string) string
+}
+
+func newBodyRedactor(o httpOptions) *bodyRedactor {
+	var keys []string
+	for _, key := range slices.Concat(sensitiveBodyKeys, o.sensitiveKeys) {
+		keys = append(keys, regexp.QuoteMeta(key))
+	}
+	alt := strings.Join(keys, "|")
+	return &bodyRedactor{
+		jsonPattern: regexp.MustCompile(`(?i)("(?:` + alt + `)"\s*:\s*)(?:"(?:[^"\\]|\\.)*"?|[^,}\]\s]*)`),
+		formPattern: regexp.MustCompile(`(?i)((?:^|&)(?:` + alt + `)=)[^&]*`),
+		custom:      o.redactBody,
+	}
+}
+
+// redact 按内容类型脱敏, 表单和 JSON 以外的文本两种规则都会尝试
+func (r *bodyRedactor) redact(contentType, body string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	isForm := mediaType == "application/x-www-form-urlencoded"
+	if !isForm {
+		body = r.jsonPattern.ReplaceAllString(body, `${1}"***"`)
+	}
+	if isForm || !isJSONContent(mediaType) {
+		body = r.formPattern.ReplaceAllString(body, `${1}***`)
+	}
+	if r.custom != nil {
+		body = r.custom(contentType, body)
+	}
+	return body
+}
+
+// isJSONContent 判断媒体类型是否为 JSON
+func isJSONContent(mediaType string) bool {
+	return strings.HasSuffix(mediaType, "/json") || strings.HasSuffix(mediaType, "+json")
+}
+
+// HTTPMiddleware 包装 next, 每个请求结束后按 LogHTTPRequest 的格式输出访问日志
+// 可以与 RecoverHTTP 组合, 放在 RecoverHTTP 外层时 panic 的请求同样会记录为 500
+func (l *Logger) HTTPMiddleware(next http.Handler, opts ...HTTPOption) http.Handler {
+	var o httpOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var redactor *bodyRedactor
+	if o.bodyBytes > 0 {
+		redactor = newBodyRedactor(o)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+
+		var reqBody *bodyCapture
+		if o.bodyBytes > 0 && r.Body != nil && r.Body != http.NoBody && isTextContent(r.Header.Get("Content-Type")) {
+			reqBody = &bodyCapture{limit: o.bodyBytes, contentType: r.Header.Get("Content-Type")}
+			r.Body = &capturingBody{ReadCloser: r.Body, capture: reqBody}
+		}
+		rw := &responseRecorder{ResponseWriter: w}
+		if o.bodyBytes > 0 {
+			rw.body = &bodyCapture{limit: o.bodyBytes}
+		}
+
+		next.ServeHTTP(rw, r)
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		ce := l.WithContext(r.Context()).zap.Check(httpStatusLevel(status), "HTTP request")
+		if ce == nil {
+			return
+		}
+		fields := httpRequestFields(r, status, rw.size, time.Since(startTime))
+		if reqBody != nil && reqBody.total > 0 {
+			fields = append(fields, zap.String("request_body", redactor.redact(reqBody.contentType, reqBody.String())))
+		}
+		if rw.body != nil && rw.body.total > 0 {
+			fields = append(fields, zap.String("response_body", redactor.redact(rw.body.contentType, rw.body.String())))
+		}
+		ce.Write(fields...)
+	})
+}
+
+// responseRecorder 记录响应的状态码、字节数和部分内容, 写入的内容原样转发
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+	// body 记录的响应内容, 为 nil 时不记录
+	body *bodyCapture
+	// checked 是否已经按内容类型判断过是否记录响应内容
+	checked bool
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.body != nil && !w.checked {
+		w.checked = true
+		contentType := w.Header().Get("Content-Type")
+		if contentType == "" {
+			contentType = http.DetectContentType(p)
+		}
+		if !isTextContent(contentType) {
+			w.body = nil
+		} else {
+			w.body.contentType = contentType
+		}
+	}
+
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	if w.body != nil {
+		w.body.Write(p[:n])
+	}
+	return n, err
+}
+
+// Flush 支持流式响应
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 支持 WebSocket 等协议升级, 原始的 ResponseWriter 不支持时返回错误
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("logger: %T does not implement http.Hijacker", w.ResponseWriter)
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Unwrap 供 http.ResponseController 访问原始的 ResponseWriter
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// capturingBody 在处理函数读取请求内容时顺带记录
+type capturingBody struct {
+	io.ReadCloser
+	capture *bodyCapture
+}
+
+func (b *capturingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.capture.Write(p[:n])
+	return n, err
+}
+
+// bodyCapture 最多保存 limit 字节的内容, 同时记录总字节数
+type bodyCapture struct {
+	buf   []byte
+	limit int
+	total int64
+	// contentType 内容的类型, 用于选择脱敏规则
+	contentType string
+}
+
+func (c *bodyCapture) Write(p []byte) {
+	c.total += int64(len(p))
+	if room := c.limit - len(c.buf); room > 0 {
+		c.buf = append(c.buf, p[:min(room, len(p))]...)
+	}
+}
+
+// String 返回保存的内容, 超出 limit 时在完整的字符处截断并标记截断的字节数
+func (c *bodyCapture) String() string {
+	if c.total <= int64(len(c.buf)) {
+		return string(c.buf)
+	}
+	cut := len(c.buf)
+	if i := lastRuneStart(c.buf); i >= 0 && !utf8.FullRune(c.buf[i:]) {
+		cut = i
+	}
+	return string(c.buf[:cut]) + truncatedMarker(int(c.total-int64(cut)))
+}
+
+// lastRuneStart 返回最后一个字符的起始位置, 没有时返回 -1
+func lastRuneStart(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isTextContent 判断内容类型是否为可以记录的文本
+func isTextContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		isJSONContent(mediaType),
+		strings.HasSuffix(mediaType, "/xml"), strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/x-www-form-urlencoded",
+		mediaType == "application/javascript":
+		return true
+	}
+	return false
+}
+
+// RecoverHTTP 包装 next, 恢复处理请求时发生的 panic, 以 Error 级别记录 panic 的值、堆栈和请求上下文, 并返回 500
+// http.ErrAbortHandler 是中止请求的约定, 会继续向上 panic; 可以与访问日志等中间件任意组合
+func (l *Logger) RecoverHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			// 无论 WithStacktraceLevel 如何配置都记录堆栈, caller 和堆栈都跳过 runtime.gopanic, 从发生 panic 的位置开始
+			l.WithContext(r.Context()).zap.WithOptions(zap.AddStacktrace(zapcore.DebugLevel), zap.AddCallerSkip(1)).Error("HTTP handler panic",
+				zap.Any("panic", rec),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("remote_ip", remoteIP(r)),
+			)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// remoteIP 从请求中解析客户端IP
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RoundTripper 包装 next, 记录每个出站请求的地址、方法、状态码和耗时, 认证相关的请求头会被脱敏
+// 请求成功时使用 WithClientLogLevel 配置的级别, 默认是 Info, 请求失败时使用 Error 级别
+func (l *Logger) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &roundTripper{next: next, logger: l}
+}
+
+// roundTripper 记录出站请求的 http.RoundTripper
+type roundTripper struct {
+	next   http.RoundTripper
+	logger *Logger
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	startTime := time.Now()
+	resp, err := rt.next.RoundTrip(req)
+	duration := time.Since(startTime)
+
+	logger := rt.logger.WithContext(req.Context())
+	fields := []zap.Field{
+		zap.String("method", req.Method),
+		zap.String("url", req.URL.Redacted()),
+		zap.Float64("duration_ms", float64(duration)/float64(time.Millisecond)),
+		zap.Object("headers", redactedHeaders(req.Header)),
+	}
+
+	if err != nil {
+		logger.zap.Error("HTTP client request failed", append(fields, zap.Error(err))...)
+		return resp, err
+	}
+
+	if ce := logger.zap.Check(rt.logger.clientLogLevel, "HTTP client request"); ce != nil {
+		ce.Write(append(fields, zap.Int("status", resp.StatusCode))...)
+	}
+	return resp, nil
+}
+
+// redactedHeaders 以对象形式记录请求头, 敏感请求头的值替换为 ***
+type redactedHeaders http.Header
+
+func (h redactedHeaders) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, name := range sortedKeys(h) {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			enc.AddString(name, "***")
+			continue
+		}
+		enc.AddString(name, strings.Join(h[name], ","))
+	}
+	return nil
+}
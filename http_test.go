@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPMiddlewareRedactsBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"user":"alice","Password":"hunter2","nested":{"access_token":"abc\"def","n":1},"api_key":12345}`,
+			want:        `{"user":"alice","Password":"***","nested":{"access_token":"***","n":1},"api_key":"***"}`,
+		},
+		{
+			name:        "truncated json",
+			contentType: "application/json",
+			body:        `{"user":"alice","password":"hunter2-with-a-long-tail"}`,
+			want:        `{"user":"alice","password":"***"`,
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user=alice&password=hunter2&token=abc",
+			want:        "user=alice&password=***&token=***",
+		},
+		{
+			name:        "custom key",
+			contentType: "application/json",
+			body:        `{"ssn":"123-45-6789"}`,
+			want:        `{"ssn":"***"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newTestLogger(t)
+			limit := 1024
+			if tt.name == "truncated json" {
+				limit = 40
+			}
+			handler := l.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+			}), WithBodyLogging(limit), WithSensitiveBodyKeys("ssn"))
+
+			req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			entries := parseTestEntries(t, buf)
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			got, _ := entries[0].String("request_body")
+			if !strings.HasPrefix(got, tt.want) {
+				t.Errorf("request_body = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareBodyRedactor(t *testing.T) {
+	l, buf := newTestLogger(t)
+	handler := l.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = io.WriteString(w, "<secret>s3cr3t</secret>")
+	}), WithBodyLogging(1024), WithBodyRedactor(func(contentType, body string) string {
+		return strings.ReplaceAll(body, "s3cr3t", "***")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	entries := parseTestEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if got, _ := entries[0].String("response_body"); got != "<secret>***</secret>" {
+		t.Errorf("response_body = %q, want <secret>***</secret>", got)
+	}
+}
+
+func TestHTTPMiddlewareHijack(t *testing.T) {
+	l, buf := newTestLogger(t)
+	handler := l.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Hijack() error = %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		_ = rw.Flush()
+	}))
+	// 连接被接管后 srv.Close 不等待处理函数返回, 通过 done 等待访问日志写入
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	<-done
+
+	entries := parseTestEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if got, _ := entries[0].Int("status"); got != http.StatusSwitchingProtocols {
+		t.Errorf("logged status = %d, want %d", got, http.StatusSwitchingProtocols)
+	}
+}
+
+func TestRecoverHTTP(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+	}{
+		{name: "development", env: Development},
+		{name: "production", env: Production},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newTestLogger(t, WithEnv(tt.env), WithReservedKeyCheck(true))
+			handler := l.RecoverHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+			}
+			entries := parseTestEntries(t, buf)
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			if got, _ := e.String("panic"); got != "boom" {
+				t.Errorf("panic = %q, want boom", got)
+			}
+			if !strings.Contains(e.Caller, "http_test.go") {
+				t.Errorf("caller = %q, want the panic site in http_test.go", e.Caller)
+			}
+			if !strings.Contains(e.Stacktrace, "TestRecoverHTTP") || strings.Contains(e.Stacktrace, "runtime.gopanic") {
+				t.Errorf("stacktrace should start at the panic site:\n%s", e.Stacktrace)
+			}
+			if _, ok := e.Fields["field_stacktrace"]; ok {
+				t.Errorf("stacktrace was logged as a renamed field")
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// EnsureRequestID 确保上下文中存在请求ID, 不存在时生成一个新的并写入上下文
+func (l *Logger) EnsureRequestID(ctx context.Context) (context.Context, string) {
+	if requestID, ok := ctx.Value(l.requestKey).(string); ok && requestID != "" {
+		return ctx, requestID
+	}
+
+	requestID := l.newID()
+	return context.WithValue(ctx, l.requestKey, requestID), requestID
+}
+
+// newID 使用配置的生成函数生成一个新的ID
+func (l *Logger) newID() string {
+	if l.idGenerator != nil {
+		return l.idGenerator()
+	}
+	return newUUID()
+}
+
+// newUUID 生成一个随机的 UUIDv4
+func newUUID() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[:])
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return string(buf[:])
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// journalSocket systemd-journald 原生协议的套接字路径
+const journalSocket = "/run/systemd/journal/socket"
+
+// journalCore 通过原生协议把日志写入 systemd-journald 的 core
+type journalCore struct {
+	zapcore.LevelEnabler
+	conn       *net.UnixConn
+	identifier string
+	fields     []zapcore.Field
+}
+
+// newJournalCore 连接 journald, 不在 systemd 环境下运行时返回 nil
+func (l *Logger) newJournalCore(enab zapcore.LevelEnabler) zapcore.Core {
+	if _, err := os.Stat(journalSocket); err != nil {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil
+	}
+	l.addCloser(conn.Close)
+
+	return &journalCore{
+		LevelEnabler: enab,
+		conn:         conn,
+		identifier:   l.serviceName,
+	}
+}
+
+func (c *journalCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return &clone
+}
+
+func (c *journalCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *journalCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", ent.Message)
+	appendJournalField(&buf, "PRIORITY", strconv.Itoa(journalPriority(ent.Level)))
+	if c.identifier != "" {
+		appendJournalField(&buf, "SYSLOG_IDENTIFIER", c.identifier)
+	}
+	if ent.Caller.Defined {
+		appendJournalField(&buf, "CODE_FILE", ent.Caller.File)
+		appendJournalField(&buf, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		appendJournalField(&buf, "CODE_FUNC", ent.Caller.Function)
+	}
+	if ent.Stack != "" {
+		appendJournalField(&buf, "STACKTRACE", ent.Stack)
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range c.fields {
+		c.fields[i].AddTo(enc)
+	}
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	for key, val := range enc.Fields {
+		name := journalFieldName(key)
+		if name == "" {
+			continue
+		}
+		appendJournalField(&buf, name, journalValue(val))
+	}
+
+	_, err := c.conn.Write(buf.Bytes())
+	return err
+}
+
+func (c *journalCore) Sync() error {
+	return nil
+}
+
+// journalPriority 把 zap 的级别映射到 syslog 优先级
+func journalPriority(lvl zapcore.Level) int {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// journalFieldName 把字段名转换为 journald 要求的大写格式, 无法转换时返回空字符串
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	// 以下划线开头的字段是 journald 保留的可信字段
+	name = strings.TrimLeft(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return ""
+	}
+	return name
+}
+
+// journalValue 把字段值格式化为字符串, 复杂类型编码为 JSON
+func journalValue(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case error:
+		return v.Error()
+	}
+	if b, err := json.Marshal(val); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(val)
+}
+
+// appendJournalField 按原生协议追加一个字段, 含换行的值使用长度前缀格式
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
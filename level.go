@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ParseLevel 解析日志级别, 除了 zap 支持的名称外还支持 warning 等别名以及 -1 到 5 的数字
+func ParseLevel(s string) (zapcore.Level, error) {
+	text := strings.ToLower(strings.TrimSpace(s))
+
+	if text == "warning" {
+		return zapcore.WarnLevel, nil
+	}
+
+	if n, err := strconv.Atoi(text); err == nil {
+		level := zapcore.Level(n)
+		if level < zapcore.DebugLevel || level > zapcore.FatalLevel {
+			return zapcore.InfoLevel, fmt.Errorf("invalid log level %q, must be between %d and %d", s, zapcore.DebugLevel, zapcore.FatalLevel)
+		}
+		return level, nil
+	}
+
+	level, err := zapcore.ParseLevel(text)
+	if err != nil {
+		return zapcore.InfoLevel, fmt.Errorf("invalid log level %q, use debug, info, warn, error, dpanic, panic or fatal", s)
+	}
+	return level, nil
+}
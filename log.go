@@ -2,28 +2,33 @@ package logger
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-var logger *Logger
+// logger 全局日志实例, 使用原子指针保证 Init 与并发的读取之间没有数据竞争
+var logger atomic.Pointer[Logger]
 
 func InitDevelopment() error {
-	var err error
-	logger, err = New(
+	l, err := New(
 		WithEnv(Development),
 		WithServiceName(ServerName),
 		WithVersionName(Version),
 		WithRequestKey(RequestKey),
 		WithUserKey(UserKey),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	logger.Store(l)
+	return nil
 }
 
 func InitProduction() error {
-	var err error
-	logger, err = New(
+	l, err := New(
 		WithEnv(Production),
 		WithServiceName(ServerName),
 		WithVersionName(Version),
@@ -36,110 +41,156 @@ func InitProduction() error {
 		WithRotateBackups(30),
 		WithRotateCompress(false),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	logger.Store(l)
+	return nil
 }
 
+// Init 使用 New 的默认配置创建全局日志实例, 为了兼容, 未设置级别时全局日志实例的级别是 Info
 func Init(opts ...Option) error {
-	logger = &Logger{
-		env:            Development,
-		serviceName:    ServerName,
-		versionName:    Version,
-		requestKey:     RequestKey,
-		userKey:        UserKey,
-		logToFile:      false,
-		rotate:         false,
-		rotatePath:     "logs/run.log",
-		rotateSize:     10,
-		rotateAge:      7,
-		rotateBackups:  10,
-		rotateCompress: false,
+	l, err := New(append([]Option{WithLevel(zapcore.InfoLevel)}, opts...)...)
+	if err != nil {
+		return err
 	}
+	logger.Store(l)
+	return nil
+}
 
-	for _, opt := range opts {
-		opt(logger)
+func MustInitDevelopment() {
+	if err := InitDevelopment(); err != nil {
+		panic("logger: init development logger failed: " + err.Error())
 	}
+}
 
-	var err error
-	logger, err = logger.newZap()
-	return err
+func MustInitProduction() {
+	if err := InitProduction(); err != nil {
+		panic("logger: init production logger failed: " + err.Error())
+	}
 }
 
-func With(fields ...zap.Field) *Logger {
-	return &Logger{zap: logger.zap.With(fields...)}
+func MustInit(opts ...Option) {
+	if err := Init(opts...); err != nil {
+		panic("logger: init logger failed: " + err.Error())
+	}
 }
 
-func WithContext(ctx context.Context) *Logger {
-	newLogger := logger.zap
+func With(fields ...zap.Field) *Logger {
+	l := logger.Load()
+	return l.withZap(l.zap.With(fields...))
+}
 
-	if requestID, ok := ctx.Value(logger.requestKey).(string); ok {
-		newLogger = newLogger.With(zap.String(logger.requestKey, requestID))
+func WithIf(cond bool, fields ...zap.Field) *Logger {
+	if !cond {
+		return logger.Load()
 	}
+	return With(fields...)
+}
 
-	if userID, ok := ctx.Value(logger.userKey).(string); ok {
-		newLogger = newLogger.With(zap.String(logger.userKey, userID))
-	}
+func WithContext(ctx context.Context) *Logger {
+	return logger.Load().WithContext(ctx)
+}
 
-	return &Logger{zap: newLogger}
+func EnsureRequestID(ctx context.Context) (context.Context, string) {
+	return logger.Load().EnsureRequestID(ctx)
 }
 
 func Debug(msg string, fields ...zap.Field) {
-	logger.zap.Debug(msg, fields...)
+	logger.Load().zap.Debug(msg, fields...)
 }
 
 func DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	logger.WithContext(ctx).Debug(msg, fields...)
+	logger.Load().WithContext(ctx).Debug(msg, fields...)
 }
 
 func Info(msg string, fields ...zap.Field) {
-	logger.zap.Info(msg, fields...)
+	logger.Load().zap.Info(msg, fields...)
 }
 
 func InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	logger.WithContext(ctx).Info(msg, fields...)
+	logger.Load().WithContext(ctx).Info(msg, fields...)
 }
 
 func Warn(msg string, fields ...zap.Field) {
-	logger.zap.Warn(msg, fields...)
+	logger.Load().zap.Warn(msg, fields...)
 }
 
 func WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	logger.WithContext(ctx).Warn(msg, fields...)
+	logger.Load().WithContext(ctx).Warn(msg, fields...)
 }
 
 func Error(msg string, err error, fields ...zap.Field) {
-	if err != nil {
-		fields = append(fields, zap.Error(err))
+	if err == nil {
+		logger.Load().zap.Error(msg, fields...)
+		return
 	}
-	logger.zap.Error(msg, fields...)
+	l := logger.Load()
+	buf := errorFields(fields, err)
+	l.errorZap(l.zap, err).Log(l.errorLevel(err), msg, *buf...)
+	putFields(buf)
 }
 
 func ErrorCtx(ctx context.Context, msg string, err error, fields ...zap.Field) {
-	logger.WithContext(ctx).Error(msg, err, fields...)
+	logger.Load().WithContext(ctx).Error(msg, err, fields...)
+}
+
+func DPanic(msg string, fields ...zap.Field) {
+	logger.Load().zap.DPanic(msg, fields...)
+}
+
+func DPanicCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	logger.Load().WithContext(ctx).DPanic(msg, fields...)
 }
 
 func Fatal(msg string, fields ...zap.Field) {
-	logger.zap.Fatal(msg, fields...)
+	logger.Load().zap.Fatal(msg, fields...)
 }
 
 func FatalCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	logger.WithContext(ctx).Fatal(msg, fields...)
+	logger.Load().WithContext(ctx).Fatal(msg, fields...)
+}
+
+func Audit(ctx context.Context, actor, action, target, result string, fields ...zap.Field) {
+	logger.Load().WithContext(ctx).zap.Info(AuditMessage, auditFields(actor, action, target, result, fields)...)
 }
 
 func Trace(ctx context.Context, funcName string) func() {
-	l := logger.WithContext(ctx)
+	l := logger.Load().WithContext(ctx)
 
 	startTime := time.Now()
 	l.Debug("Starting function", zap.String("function", funcName))
 
 	return func() {
 		duration := time.Since(startTime)
-		l.Debug("Finished function",
+		fields := []zap.Field{
 			zap.String("function", funcName),
 			zap.Duration("duration", duration),
-		)
+		}
+		l.Debug("Finished function", append(fields, deadlineFields(ctx)...)...)
 	}
 }
 
+func TraceMem(ctx context.Context, funcName string) func() {
+	return logger.Load().TraceMem(ctx, funcName)
+}
+
 func Sync() error {
-	return logger.zap.Sync()
+	return logger.Load().zap.Sync()
+}
+
+func SyncContext(ctx context.Context) error {
+	return logger.Load().SyncContext(ctx)
+}
+
+func Rotate() error {
+	return logger.Load().Rotate()
+}
+
+func Close() error {
+	return logger.Load().Close()
+}
+
+func Shutdown(ctx context.Context) error {
+	return logger.Load().Shutdown(ctx)
 }
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// initTestLogger 把全局日志实例替换为只写入内存缓冲的实例, 测试结束后恢复
+func initTestLogger(t *testing.T, opts ...Option) *bytes.Buffer {
+	t.Helper()
+	old := logger.Load()
+	t.Cleanup(func() { logger.Store(old) })
+
+	var buf bytes.Buffer
+	if err := Init(append([]Option{WithConsoleLevel(zapcore.FatalLevel), WithWriter(&buf)}, opts...)...); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	return &buf
+}
+
+func TestErrorReusedFields(t *testing.T) {
+	buf := initTestLogger(t)
+
+	// 预留容量, 直接 append 时第一次的错误字段会留在底层数组中
+	fields := make([]zap.Field, 1, 4)
+	fields[0] = zap.String("key", "value")
+	Error("a", errors.New("err1"), fields...)
+	Error("b", errors.New("err2"), fields...)
+
+	if n := strings.Count(buf.String(), "err1"); n != 1 {
+		t.Errorf("err1 appears %d times, want 1", n)
+	}
+	entries := parseTestEntries(t, buf)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if got := entries[1].Fields["error"]; got != "err2" {
+		t.Errorf("second entry error = %v, want err2", got)
+	}
+	if got := entries[1].Fields["key"]; got != "value" {
+		t.Errorf("second entry key = %v, want value", got)
+	}
+}
+
+// TestInitConcurrentWith 需要使用 go test -race 运行, 检查 Init 与全局 With、WithContext 之间没有数据竞争
+func TestInitConcurrentWith(t *testing.T) {
+	initTestLogger(t)
+	opts := []Option{WithConsoleLevel(zapcore.FatalLevel), WithWriter(io.Discard)}
+	ctx := context.WithValue(context.Background(), RequestKey, "req-1")
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				With(zap.Int("i", i)).Info("with")
+				WithContext(ctx).Info("with context")
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := Init(opts...); err != nil {
+			t.Errorf("Init() error = %v", err)
+			break
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
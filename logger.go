@@ -1,11 +1,27 @@
 package logger
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/natefinch/lumberjack"
 	"go.uber.org/zap"
@@ -21,7 +37,33 @@ const (
 	Version     = "v1.0.0"
 )
 
+const (
+	// PodNameEnv Kubernetes downward API 设置 Pod 名称的默认环境变量
+	PodNameEnv = "POD_NAME"
+	// PodNamespaceEnv Kubernetes downward API 设置命名空间的默认环境变量
+	PodNamespaceEnv = "POD_NAMESPACE"
+	// NodeNameEnv Kubernetes downward API 设置节点名称的默认环境变量
+	NodeNameEnv = "NODE_NAME"
+)
+
+const (
+	// TimeLayout 默认的时间格式
+	TimeLayout = "2006-01-02 15:04:05.000Z0700"
+	// TimeLayoutRFC3339Z RFC3339 时间格式, UTC 时间以 Z 结尾, 例如：2024-01-02T03:04:05.000Z
+	TimeLayoutRFC3339Z = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// Logger 日志实例, With 等方法派生的实例共享创建时的配置, 只复制组件名和 zap 实例
 type Logger struct {
+	*config
+	// component 服务内的组件名, 例如：auth、billing
+	component string
+	// zap 日志库的实例
+	zap *zap.Logger
+}
+
+// config 日志实例创建后不再修改的配置和共享的运行状态, Clone 时复制一份新的配置
+type config struct {
 	// env 服务的环境, development or production
 	env string
 	// level 存储日志级别
@@ -30,10 +72,26 @@ type Logger struct {
 	serviceName string
 	// versionName 服务版本, 例如：v1.0.0
 	versionName string
+	// schemaVersion 日志格式的版本, 作为 schema 基础字段输出, 为空时不输出
+	schemaVersion string
+	// kubernetesFields 是否从 downward API 设置的环境变量读取 pod、namespace、node 基础字段
+	kubernetesFields bool
+	// podNameEnv、podNamespaceEnv、nodeNameEnv 读取的环境变量名, 为空时使用默认的名称
+	podNameEnv      string
+	podNamespaceEnv string
+	nodeNameEnv     string
+	// buildInfo 是否添加构建信息中的 vcs_revision 和 vcs_time 字段
+	buildInfo bool
 	// requestKey 请求上下文的请求ID名称, 例如：request_id
 	requestKey string
 	// userKey 请求上下文的用户ID名称, 例如：user_id
 	userKey string
+	// caller 是否输出调用位置, 默认输出
+	caller bool
+	// stacktraceLevel 输出堆栈的最低级别, 默认是 Error
+	stacktraceLevel zapcore.Level
+	// callerLevel 输出 caller 的最低级别, 为 nil 时所有级别都输出
+	callerLevel zapcore.LevelEnabler
 	// logToFile 是否打印日志到文件, 默认是标准输出
 	logToFile bool
 	// rotate 是否开启日志文件分割, 默认不开启
@@ -48,8 +106,170 @@ type Logger struct {
 	rotateBackups int
 	// rotateCompress 是否压缩日志文件, 默认是不压缩
 	rotateCompress bool
-	// zap 日志库的实例
-	zap *zap.Logger
+	// consoleLevel 标准输出的最低级别, 在日志实例的级别之上进一步过滤, 默认不额外过滤
+	consoleLevel zapcore.Level
+	// fileLevel 日志文件的最低级别, 在日志实例的级别之上进一步过滤, 默认不额外过滤
+	fileLevel zapcore.Level
+	// splitStreams 是否把 Error 及以上级别的日志写入标准错误, 其他级别写入标准输出, 默认全部写入标准输出
+	splitStreams bool
+	// consoleEncoding 标准输出是否使用易读的文本格式, 默认和文件一样使用 JSON
+	consoleEncoding bool
+	// eventLogSource Windows 事件日志的事件源名称, 为空时不写入事件日志
+	eventLogSource string
+	// humanFile 额外写入文本格式日志的文件, 为空时不写入
+	humanFile string
+	// writers 通过 WithWriter/AddWriter 添加的输出目标, 按添加的顺序写入
+	writers []writerSink
+	// crashFile 额外记录 Panic 和 Fatal 日志的文件, 为空时不记录
+	crashFile string
+	// unixSocket 同时按行写入 JSON 日志的 unix 套接字路径, 为空时不写入
+	unixSocket string
+	// networkSinks 通过 WithNetworkSink 添加的网络输出
+	networkSinks []networkSink
+	// sinkWriteTimeout 网络输出每次写入的超时时间, 默认1秒, 为 0 时不限制
+	sinkWriteTimeout time.Duration
+	// networkStats 网络输出的统计计数, 有网络输出时创建
+	networkStats *networkStats
+	// journald 是否同时写入 systemd-journald, 不在 systemd 环境下时自动忽略
+	journald bool
+	// development 是否使用 zap 的开发模式, DPanic 日志输出后会 panic, 为 nil 时跟随 env
+	development *bool
+	// lowercaseLevel 级别是否输出为小写, 为 nil 时跟随 env, 开发环境大写、生产环境小写
+	lowercaseLevel *bool
+	// onFatal Fatal 日志写入后、进程退出前执行的回调, 用于刷新指标、关闭连接等清理工作
+	onFatal func()
+	// panicOnFatal Fatal 日志写入后是否 panic 而不是退出进程, 便于测试
+	panicOnFatal bool
+	// exitFunc Fatal 日志写入后退出进程的函数, 默认是 os.Exit, 测试时可以替换为不退出的函数
+	exitFunc func(int)
+	// uptime 是否在每条日志中添加 uptime 字段, 表示日志实例创建以来的秒数
+	uptime bool
+	// sequence 是否在每条日志中添加单调递增的 seq 字段, 用于还原日志的输出顺序
+	sequence bool
+	// timeKey 格式化时间的字段名, 默认是 time, 为空时不输出
+	timeKey string
+	// epochKey 数值时间戳的字段名, 值为 Unix 秒数, 为空时不输出
+	epochKey string
+	// timePrecision 时间的精度, 为 0 时使用时间格式本身的毫秒精度
+	timePrecision time.Duration
+	// timeUTC 是否以 UTC 时区输出时间
+	timeUTC bool
+	// timeRFC3339Z 是否使用 RFC3339 时间格式, 配合 timeUTC 输出以 Z 结尾的时间
+	timeRFC3339Z bool
+	// lineEnding 每条日志的结尾分隔符, 默认是 \n
+	lineEnding string
+	// stackdriver 是否使用 GCP Cloud Logging 的格式输出 severity 和 sourceLocation
+	stackdriver bool
+	// callerEncoder 调用位置的编码方式, 为空时使用 zap 环境配置的默认值
+	callerEncoder zapcore.CallerEncoder
+	// callerTrimPrefix 输出调用位置时去掉的文件路径前缀, 例如模块根目录
+	callerTrimPrefix string
+	// messageKey 日志消息的字段名, 默认是 message, 为空时不输出消息字段
+	messageKey string
+	// deprecationLimit 每个废弃特性最多输出的警告次数, 默认是3次
+	deprecationLimit int
+	// idGenerator 生成请求ID的函数, 会被并发调用, 默认生成 UUIDv4
+	idGenerator func() string
+	// contextStatus 上下文已取消或超时时是否在日志中添加 ctx_err 字段
+	contextStatus bool
+	// clientLogLevel RoundTripper 记录成功的出站请求时使用的级别, 默认是 Info
+	clientLogLevel zapcore.Level
+	// pprofLabelKeys 从上下文的 pprof 标签中读取并添加为字段的键
+	pprofLabelKeys []string
+	// lazyContextKeys 每条日志写入时才从上下文读取的键, 用于在请求过程中会变化的值
+	lazyContextKeys []string
+	// debugHeader 开启单个请求调试日志的请求头, 默认是 X-Debug
+	debugHeader string
+	// samplerFunc 对每条日志做采样决策的函数, 返回 zapcore.LogDropped 时丢弃该条日志
+	samplerFunc func(ent zapcore.Entry) zapcore.SamplingDecision
+	// redactFunc 自定义脱敏函数, 对每个字段调用, 返回 true 时使用返回的值替换原值
+	redactFunc func(key string, val interface{}) (interface{}, bool)
+	// roundFloats 是否对浮点数字段按 floatPrecision 保留小数位数
+	roundFloats bool
+	// floatPrecision 浮点数字段保留的小数位数
+	floatPrecision int
+	// downgradeErrors 决定 Error 记录错误时使用的级别, 用于把预期内的错误降级为 Warn 等级别
+	downgradeErrors func(error) zapcore.Level
+	// samplingInitial 开启采样时每个周期内同一消息全部输出的条数
+	samplingInitial int
+	// samplingThereafter 超过 samplingInitial 后每多少条输出一条, 为0时全部丢弃
+	samplingThereafter int
+	// samplingTick 采样计数的周期, 默认是1秒
+	samplingTick time.Duration
+	// samplingKey 按字段值采样时使用的字段名
+	samplingKey string
+	// samplingKeyInitial 按字段值采样时每个周期内同一字段值全部输出的条数
+	samplingKeyInitial int
+	// samplingKeyThereafter 按字段值采样时超过 samplingKeyInitial 后每多少条输出一条
+	samplingKeyThereafter int
+	// samplingByRequest 是否按请求ID分别计数采样, 优先于 samplingKey
+	samplingByRequest bool
+	// samplingExclude 返回 true 的日志不经过任何采样, 例如审计日志
+	samplingExclude func(ent zapcore.Entry) bool
+	// keySampler 按字段值采样的采样器, 开启按字段值采样时创建
+	keySampler *countSampler
+	// sampler 按计数采样的采样器, 开启采样时创建
+	sampler *countSampler
+	// messagePrefix 添加到每条日志消息前的前缀, 为空时不添加
+	messagePrefix string
+	// sanitizeNewlines 是否把消息和字符串字段中的换行替换为 \n 转义
+	sanitizeNewlines bool
+	// maxReflectDepth zap.Any 等反射编码的字段允许的最大嵌套深度, 默认5, 为 0 时不限制
+	maxReflectDepth int
+	// maxFieldSize 单个字符串或字节字段的最大字节数, 超过时截断并添加标记, 为0时不限制
+	maxFieldSize int
+	// requiredFields 开发环境下每条日志必须包含的字段, 缺少时输出警告
+	requiredFields []string
+	// noStacktraceFor 匹配的错误在 Error 中不输出堆栈, 例如上下文取消
+	noStacktraceFor func(error) bool
+	// samplingStats 采样的统计计数, 开启采样时创建
+	samplingStats *samplingStats
+	// reservedKeyCheck 是否检查字段使用了 level、time、message 等保留键
+	reservedKeyCheck bool
+	// includeFields 只输出这些键的字段, 为空时不限制
+	includeFields map[string]struct{}
+	// excludeFields 不输出这些键的字段, 优先于 includeFields
+	excludeFields map[string]struct{}
+	// prettyJSON 开发环境下是否缩进输出到标准输出的 JSON 日志
+	prettyJSON bool
+	// callerPackage 是否输出调用方所在的包路径
+	callerPackage bool
+	// dedupeFields 是否按键合并 With 和日志的字段, 同名字段只保留最后设置的值
+	dedupeFields bool
+	// traceMemRate TraceMem 读取内存统计的比例, 0 到 1 之间, 为 0 时不读取
+	traceMemRate float64
+	// audit 是否为审计模式, 写入日志文件后立即 fsync 并且不参与采样, 由 NewAudit 开启
+	audit bool
+	// startupLog 创建后是否输出一条描述实际配置的 Info 日志
+	startupLog bool
+	// syncInterval 定期 Sync 的间隔, 为 0 时不定期 Sync
+	syncInterval time.Duration
+	// hexBinary 是否把 zap.Binary 字段编码为十六进制而不是 base64
+	hexBinary bool
+	// collapseRepeats 是否把连续重复的日志合并为一条
+	collapseRepeats bool
+	// alertLevel 触发告警回调的最低级别
+	alertLevel zapcore.Level
+	// alertCooldown 两次告警之间的最短间隔
+	alertCooldown time.Duration
+	// alertFunc 告警回调, 为 nil 时不触发告警
+	alertFunc func(zapcore.Entry, []zapcore.Field)
+	// bytesWritten 所有输出目标累计写入的字节数, 创建时初始化, 派生的实例共享
+	bytesWritten *atomic.Uint64
+	// levelStats 各级别的日志条数, 创建时初始化, 派生的实例共享
+	levelStats *levelStats
+	// errorOutput zap 内部错误的输出目标, 例如写入日志失败, 为 nil 时使用标准错误
+	errorOutput zapcore.WriteSyncer
+	// extraZapOptions 创建 zap 实例时额外传入的选项
+	extraZapOptions []zap.Option
+	// rotators 写入轮转日志文件的 lumberjack 实例, 用于手动触发轮转
+	rotators []*lumberjack.Logger
+	// optionErr 应用选项时发生的错误, 创建日志实例时返回
+	optionErr error
+	// closers 日志实例打开的文件等资源, Close 时关闭
+	closers *closers
+	// closeGuard 记录是否已经关闭, 创建时初始化, 派生的实例共享
+	closeGuard *closeGuard
 }
 
 type Option func(*Logger)
@@ -66,6 +286,18 @@ func WithLevel(level zapcore.Level) Option {
 	}
 }
 
+// WithLevelString 按 ParseLevel 解析级别字符串, 例如来自命令行参数或配置文件的值, 无法解析时 New 返回该错误
+func WithLevelString(s string) Option {
+	return func(l *Logger) {
+		level, err := ParseLevel(s)
+		if err != nil {
+			l.optionErr = err
+			return
+		}
+		l.level = level
+	}
+}
+
 func WithServiceName(serviceName string) Option {
 	return func(l *Logger) {
 		l.serviceName = serviceName
@@ -78,6 +310,43 @@ func WithVersionName(versionName string) Option {
 	}
 }
 
+func WithComponent(component string) Option {
+	return func(l *Logger) {
+		l.component = component
+	}
+}
+
+// WithSchemaVersion 添加 schema 基础字段, 记录日志格式的版本, 与服务的 version 无关
+// 日志格式变化时递增该版本, 下游解析可以按版本区分处理
+func WithSchemaVersion(schemaVersion string) Option {
+	return func(l *Logger) {
+		l.schemaVersion = schemaVersion
+	}
+}
+
+// WithKubernetesFields 添加 pod、namespace、node 基础字段, 值取自 Kubernetes downward API 设置的环境变量,
+// 默认读取 POD_NAME、POD_NAMESPACE、NODE_NAME, 未设置的环境变量直接跳过
+func WithKubernetesFields(kubernetesFields bool) Option {
+	return func(l *Logger) {
+		l.kubernetesFields = kubernetesFields
+	}
+}
+
+// WithKubernetesEnvNames 设置 WithKubernetesFields 读取的环境变量名, 为空的参数使用默认的名称
+func WithKubernetesEnvNames(podName, podNamespace, nodeName string) Option {
+	return func(l *Logger) {
+		l.podNameEnv = podName
+		l.podNamespaceEnv = podNamespace
+		l.nodeNameEnv = nodeName
+	}
+}
+
+func WithBuildInfo(buildInfo bool) Option {
+	return func(l *Logger) {
+		l.buildInfo = buildInfo
+	}
+}
+
 func WithRequestKey(requestKey string) Option {
 	return func(l *Logger) {
 		l.requestKey = requestKey
@@ -90,6 +359,27 @@ func WithUserKey(userKey string) Option {
 	}
 }
 
+func WithCaller(caller bool) Option {
+	return func(l *Logger) {
+		l.caller = caller
+	}
+}
+
+func WithStacktraceLevel(stacktraceLevel zapcore.Level) Option {
+	return func(l *Logger) {
+		l.stacktraceLevel = stacktraceLevel
+	}
+}
+
+// WithCallerLevel 只为不低于 callerLevel 的日志输出 caller, 例如 Warn 以下的日志不输出 caller, 减小 Info 日志的体积
+// 与 WithStacktraceLevel 配合可以只为 Warn 以上的日志保留完整的上下文;
+// caller 仍然会在写入前获取, 只是不写入输出, 因此节省的是日志体积而不是获取 caller 的开销
+func WithCallerLevel(callerLevel zapcore.Level) Option {
+	return func(l *Logger) {
+		l.callerLevel = callerLevel
+	}
+}
+
 func WithLogToFile(logToFile bool) Option {
 	return func(l *Logger) {
 		l.logToFile = logToFile
@@ -132,319 +422,1788 @@ func WithRotateCompress(rotateCompress bool) Option {
 	}
 }
 
-func NewDevelopment() (*Logger, error) {
-	return New(
-		WithEnv(Development),
-		WithLevel(zapcore.DebugLevel),
-		WithServiceName(ServerName),
-		WithVersionName(Version),
-		WithRequestKey(RequestKey),
-		WithUserKey(UserKey),
-	)
+// WithConsoleLevel 设置标准输出的最低级别, 例如日志实例为 Debug 时标准输出只保留 Info 及以上
+func WithConsoleLevel(consoleLevel zapcore.Level) Option {
+	return func(l *Logger) {
+		l.consoleLevel = consoleLevel
+	}
 }
 
-func NewProduction() (*Logger, error) {
-	return New(
-		WithEnv(Production),
-		WithLevel(zapcore.InfoLevel),
-		WithServiceName(ServerName),
-		WithVersionName(Version),
-		WithRequestKey(RequestKey),
-		WithUserKey(UserKey),
-		WithRotate(true),
-		WithRotatePath("logs/run.log"),
-		WithRotateSize(10),
-		WithRotateAge(30),
-		WithRotateBackups(30),
-		WithRotateCompress(false),
-	)
+// WithFileLevel 设置日志文件的最低级别, 与 WithConsoleLevel 配合可以让文件保留完整的 Debug 日志
+func WithFileLevel(fileLevel zapcore.Level) Option {
+	return func(l *Logger) {
+		l.fileLevel = fileLevel
+	}
 }
 
-func New(opts ...Option) (*Logger, error) {
-	l := &Logger{
-		env:            Development,
-		level:          zapcore.DebugLevel,
-		serviceName:    ServerName,
-		versionName:    Version,
-		requestKey:     RequestKey,
-		userKey:        UserKey,
-		logToFile:      false,
-		rotate:         false,
-		rotatePath:     "logs/run.log",
-		rotateSize:     10,
-		rotateAge:      7,
-		rotateBackups:  10,
-		rotateCompress: false,
+// WithTraceMemSampling 设置 TraceMem 读取内存统计的比例, 例如 0.01 表示约 1% 的调用记录内存分配, 默认 0 不读取
+// 读取内存统计的开销较大, 只在排查问题时开启
+func WithTraceMemSampling(rate float64) Option {
+	return func(l *Logger) {
+		l.traceMemRate = rate
 	}
+}
 
-	for _, opt := range opts {
-		opt(l)
+// WithStartupLog 创建日志实例后通过自身输出一条 Info 日志, 描述实际生效的环境、级别、输出目标和轮转配置
+// 便于确认日志按预期配置, 默认关闭, 避免作为库使用时输出意外的日志
+func WithStartupLog(startupLog bool) Option {
+	return func(l *Logger) {
+		l.startupLog = startupLog
 	}
+}
 
-	return l.newZap()
+// WithPeriodicSync 每隔 interval 调用一次 Sync, 让写入日志文件的内容及时落盘, 便于 tail -f 查看, Close 时停止
+func WithPeriodicSync(interval time.Duration) Option {
+	return func(l *Logger) {
+		l.syncInterval = interval
+	}
 }
 
-func (l *Logger) With(fields ...zap.Field) *Logger {
-	return &Logger{zap: l.zap.With(fields...)}
+// WithDedupeFields 按键合并基础字段、上下文字段和日志字段, 同名字段只输出最后设置的值
+// 开启后 With 的字段不再预先编码, 每条日志都会重新编码一次, 有一定性能开销
+func WithDedupeFields(dedupeFields bool) Option {
+	return func(l *Logger) {
+		l.dedupeFields = dedupeFields
+	}
 }
 
-func (l *Logger) WithContext(ctx context.Context) *Logger {
-	newLogger := l.zap
+// WithCallerPackage 为每条日志添加 package 字段, 值为调用方所在的包路径, 便于按代码归属过滤日志
+// 使用与 caller 相同的调用栈位置, 关闭 WithCaller 时不输出
+func WithCallerPackage(callerPackage bool) Option {
+	return func(l *Logger) {
+		l.callerPackage = callerPackage
+	}
+}
 
-	if requestID, ok := ctx.Value(l.requestKey).(string); ok {
-		newLogger = newLogger.With(zap.String(l.requestKey, requestID))
+// WithReservedKeyCheck 检查 With 和日志的字段是否使用了 level、time、message、caller、stacktrace 等保留键
+// 开发模式下直接 panic, 尽早发现问题; 其他情况下为字段名添加 field_ 前缀, 避免覆盖日志本身的字段
+func WithReservedKeyCheck(reservedKeyCheck bool) Option {
+	return func(l *Logger) {
+		l.reservedKeyCheck = reservedKeyCheck
 	}
+}
 
-	if userID, ok := ctx.Value(l.userKey).(string); ok {
-		newLogger = newLogger.With(zap.String(l.userKey, userID))
+// WithFieldFilter 按键过滤输出的字段, include 不为空时只保留其中的字段, exclude 中的字段总是丢弃
+// 可以为次要的输出目标去掉基数过高的字段, 而不需要修改调用方
+func WithFieldFilter(include, exclude []string) Option {
+	return func(l *Logger) {
+		l.includeFields = keySet(include)
+		l.excludeFields = keySet(exclude)
 	}
+}
 
-	return &Logger{zap: newLogger}
+// WithPrettyJSON 开发环境下把输出到标准输出的 JSON 日志缩进为多行, 便于本地调试时查看完整的结构
+// 每条日志都要重新解析一次, 开销较大, 只在开发环境生效, 日志文件和生产环境不受影响
+func WithPrettyJSON(prettyJSON bool) Option {
+	return func(l *Logger) {
+		l.prettyJSON = prettyJSON
+	}
 }
 
-func (l *Logger) Debug(msg string, fields ...zap.Field) {
-	l.zap.Debug(msg, fields...)
+// WithErrorOutput 设置 zap 内部错误的输出目标, 例如日志文件写入失败时的错误, 默认输出到标准错误
+func WithErrorOutput(errorOutput zapcore.WriteSyncer) Option {
+	return func(l *Logger) {
+		l.errorOutput = errorOutput
+	}
 }
 
-func (l *Logger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	l.WithContext(ctx).zap.Debug(msg, fields...)
+// WithZapOptions 创建 zap 实例时额外传入 zap 的选项, 用于本包没有单独封装的功能, 例如 zap.Hooks
+// 这些选项在默认选项之后应用, 可能覆盖 WithCaller、WithStacktraceLevel 等设置;
+// zap.Hooks 和 zap.WrapCore 包装的 core 位于级别过滤之外, AtLevel 对其不再生效
+func WithZapOptions(opts ...zap.Option) Option {
+	return func(l *Logger) {
+		l.extraZapOptions = append(l.extraZapOptions, opts...)
+	}
 }
 
-func (l *Logger) Info(msg string, fields ...zap.Field) {
-	l.zap.Info(msg, fields...)
+// WithAlertHook 在日志级别不低于 level 时调用 fn, 例如推送到告警平台, cooldown 内只触发第一次, 避免告警风暴
+// fn 在新的协程中执行, 不阻塞日志写入; 传给 fn 的字段是该条日志自身的字段, 不包含 With 添加的字段
+func WithAlertHook(level zapcore.Level, cooldown time.Duration, fn func(zapcore.Entry, []zapcore.Field)) Option {
+	return func(l *Logger) {
+		l.alertLevel = level
+		l.alertCooldown = cooldown
+		l.alertFunc = fn
+	}
 }
 
-func (l *Logger) InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	l.WithContext(ctx).zap.Info(msg, fields...)
+// WithHexBinary 把 zap.Binary 创建的字段编码为十六进制字符串, 默认是 base64, 单个字段也可以使用 HexField
+func WithHexBinary(hexBinary bool) Option {
+	return func(l *Logger) {
+		l.hexBinary = hexBinary
+	}
 }
 
-func (l *Logger) Warn(msg string, fields ...zap.Field) {
-	l.zap.Warn(msg, fields...)
+// WithCollapseRepeats 把连续重复的相同日志合并为一条, 附带 repeated 字段记录重复次数, 常用于重试循环
+// 最近的一条日志会暂存到下一条不同的日志到来或 Sync、Close 时才写出, 可以配合 WithPeriodicSync 定期写出
+func WithCollapseRepeats(collapseRepeats bool) Option {
+	return func(l *Logger) {
+		l.collapseRepeats = collapseRepeats
+	}
 }
 
-func (l *Logger) WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	l.WithContext(ctx).zap.Warn(msg, fields...)
+// WithDevelopment 单独设置是否使用 zap 的开发模式, 开发模式下 DPanic 日志输出后会 panic
+// 默认开发环境开启、生产环境关闭, 例如预发环境可以使用生产环境的格式, 同时让 DPanic 触发 panic
+func WithDevelopment(development bool) Option {
+	return func(l *Logger) {
+		l.development = &development
+	}
 }
 
-func (l *Logger) Error(msg string, err error, fields ...zap.Field) {
-	if err != nil {
-		fields = append(fields, zap.Error(err))
+// WithLowercaseLevel 设置级别输出为小写的 info、error 还是大写的 INFO、ERROR, 对所有输出统一生效
+// 默认开发环境大写、生产环境小写; 开启 WithStackdriver 时使用 Cloud Logging 的 severity, 不受该选项影响
+func WithLowercaseLevel(lowercaseLevel bool) Option {
+	return func(l *Logger) {
+		l.lowercaseLevel = &lowercaseLevel
 	}
-	l.zap.Error(msg, fields...)
 }
 
-func (l *Logger) ErrorCtx(ctx context.Context, msg string, err error, fields ...zap.Field) {
-	if err != nil {
-		fields = append(fields, zap.Error(err))
+// WithSplitStreams 把 Error 及以上级别的日志写入标准错误, Debug、Info、Warn 写入标准输出
+// 只影响输出到终端的部分, WithConsoleLevel 对两者同样生效
+func WithSplitStreams(splitStreams bool) Option {
+	return func(l *Logger) {
+		l.splitStreams = splitStreams
 	}
-	l.WithContext(ctx).zap.Error(msg, fields...)
 }
 
-func (l *Logger) Fatal(msg string, fields ...zap.Field) {
-	l.zap.Fatal(msg, fields...)
+func WithConsoleEncoding(consoleEncoding bool) Option {
+	return func(l *Logger) {
+		l.consoleEncoding = consoleEncoding
+	}
 }
 
-func (l *Logger) FatalCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	l.WithContext(ctx).zap.Fatal(msg, fields...)
+func WithWindowsEventLog(source string) Option {
+	return func(l *Logger) {
+		l.eventLogSource = source
+	}
 }
 
-func (l *Logger) Trace(ctx context.Context, funcName string) func() {
-	logger := l.WithContext(ctx)
+// WithHumanFile 额外把日志以易读的文本格式写入 path, 便于登录机器后直接用 less 查看
+// 该文件使用 WithRotateSize 等轮转配置, 与主日志文件各自独立轮转, 级别与 WithFileLevel 相同
+func WithHumanFile(path string) Option {
+	return func(l *Logger) {
+		l.humanFile = path
+	}
+}
 
-	startTime := time.Now()
-	logger.Debug("Starting function", zap.String("function", funcName))
+// writerSink 通过 WithWriter/AddWriter 添加的输出目标
+type writerSink struct {
+	w       io.Writer
+	enab    zapcore.LevelEnabler
+	encoder zapcore.Encoder
+}
 
-	return func() {
-		duration := time.Since(startTime)
-		logger.Debug("Finished function",
-			zap.String("function", funcName),
-			zap.Duration("duration", duration),
-		)
+// WithWriter 添加一个写入 JSON 日志的输出目标, 与标准输出、日志文件等其他输出同时写入
+// 可以多次调用, 每次添加一个输出目标, 按添加的顺序写入; w 实现了 zapcore.WriteSyncer 时 Sync 会同时刷新 w
+func WithWriter(w io.Writer) Option {
+	return AddWriter(w, nil, nil)
+}
+
+// AddWriter 与 WithWriter 相同, 但可以为该输出目标单独指定级别和编码器, 为 nil 时使用默认值
+// 级别在日志实例的级别之上进一步过滤; 每次调用需要传入新的编码器, 不要在多个输出目标之间共用
+func AddWriter(w io.Writer, enab zapcore.LevelEnabler, encoder zapcore.Encoder) Option {
+	return func(l *Logger) {
+		l.writers = append(l.writers, writerSink{w: w, enab: enab, encoder: encoder})
 	}
 }
 
-func (l *Logger) Sync() error {
-	return l.zap.Sync()
+// WithCrashFile 把 Panic 和 Fatal 日志额外写入 path, 该文件不会轮转, 主日志轮转后仍然可以找到最后一次崩溃的日志
+func WithCrashFile(path string) Option {
+	return func(l *Logger) {
+		l.crashFile = path
+	}
 }
 
-func (l *Logger) newZap() (*Logger, error) {
-	zapFields := []zap.Field{
-		zap.String("env", l.env),
+// WithUnixSocket 同时把 JSON 日志按行写入 unix 套接字, 例如节点上的日志采集器
+// 连接断开时自动按退避间隔重连, 期间最多在内存中暂存 1MB 日志
+func WithUnixSocket(path string) Option {
+	return func(l *Logger) {
+		l.unixSocket = path
 	}
-	if l.serviceName != "" {
-		zapFields = append(zapFields, zap.String("service", l.serviceName))
+}
+
+// WithNetworkSink 添加一个按行写入 JSON 日志的网络输出, 例如 syslog、TCP 采集器或 Loki 的推送代理
+// 连接正常时直接写入连接; 断线时在内存中最多暂存 bufferBytes 字节的日志, 按退避间隔重连, 缓冲已满时按 dropPolicy 丢弃
+// 单条超过 bufferBytes 的日志只在连接正常时写出, 断线时无法暂存, 直接丢弃; bufferBytes 为 0 时断线期间的日志全部丢弃
+// 投递是尽力而为的: 缓冲溢出、进程退出或 Close 时仍未写出的日志会丢失, 不保证至少一次;
+// 写入失败的那条日志会在重连后重发, 接收端可能看到重复或残缺的行, 也不保证最多一次
+// 丢弃和重连次数可以通过 NetworkDroppedTotal 和 NetworkReconnectsTotal 获取
+func WithNetworkSink(dialer Dialer, bufferBytes int, dropPolicy DropPolicy) Option {
+	return func(l *Logger) {
+		l.networkSinks = append(l.networkSinks, networkSink{dial: dialer, bufferBytes: bufferBytes, dropPolicy: dropPolicy})
 	}
-	if l.versionName != "" {
-		zapFields = append(zapFields, zap.String("version", l.versionName))
+}
+
+// WithSinkWriteTimeout 设置网络输出每次写入的超时时间, 默认1秒, 为 0 时不限制, 避免采集端卡住时阻塞记录日志
+// 写入超时按连接断开处理: 该条日志保留在缓冲中, 按退避间隔重连后重发, 期间的日志只写入缓冲, 缓冲已满时按丢弃策略丢弃
+// 该超时同样用于连接 unix 套接字; WithNetworkSink 的 dialer 由调用方提供, 需要自行设置连接超时
+func WithSinkWriteTimeout(sinkWriteTimeout time.Duration) Option {
+	return func(l *Logger) {
+		l.sinkWriteTimeout = sinkWriteTimeout
 	}
+}
 
-	switch l.env {
-	case Development:
-		zapLogger, err := l.newZapDevelopment(zapFields...)
-		if err != nil {
-			return nil, err
-		}
-		l.zap = zapLogger
-		return l, nil
-	case Production:
-		zapLogger, err := l.newZapProduction(zapFields...)
-		if err != nil {
-			return nil, err
-		}
-		l.zap = zapLogger
-		return l, nil
+func WithJournald(journald bool) Option {
+	return func(l *Logger) {
+		l.journald = journald
 	}
+}
 
-	return nil, errors.New("invalid environment,  use development or production")
+func WithOnFatal(onFatal func()) Option {
+	return func(l *Logger) {
+		l.onFatal = onFatal
+	}
 }
 
-func (l *Logger) newZapDevelopment(fields ...zap.Field) (*zap.Logger, error) {
-	config := zap.NewDevelopmentConfig()
-	config.EncoderConfig.LevelKey = "level"
-	config.EncoderConfig.TimeKey = "time"
-	config.EncoderConfig.MessageKey = "message"
-	config.EncoderConfig.CallerKey = "caller"
-	config.EncoderConfig.StacktraceKey = "stacktrace"
-	// config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	config.EncoderConfig.EncodeTime = formatTime
-	// 应用级别
-	config.Level = zap.NewAtomicLevelAt(l.level)
+func WithPanicOnFatal(panicOnFatal bool) Option {
+	return func(l *Logger) {
+		l.panicOnFatal = panicOnFatal
+	}
+}
 
-	if !l.logToFile {
-		encoder := zapcore.NewJSONEncoder(config.EncoderConfig)
-		core := zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), config.Level)
-
-		logger := zap.New(
-			core,
-			zap.AddCaller(),
-			zap.AddCallerSkip(1),
-			zap.AddStacktrace(zapcore.ErrorLevel),
-			zap.Fields(
-				fields...,
-			),
-		)
-		return logger, nil
+func WithExitFunc(exitFunc func(int)) Option {
+	return func(l *Logger) {
+		l.exitFunc = exitFunc
 	}
+}
 
-	if l.rotate {
-		logWriter := l.getLogWriter()
-		encoder := zapcore.NewJSONEncoder(config.EncoderConfig)
-		fileCore := zapcore.NewCore(encoder, logWriter, config.Level)
-
-		consoleWriter := zapcore.Lock(os.Stdout)
-		consoleCore := zapcore.NewCore(encoder, consoleWriter, config.Level)
-		core := zapcore.NewTee(fileCore, consoleCore)
-
-		logger := zap.New(
-			core,
-			zap.AddCaller(),
-			zap.AddCallerSkip(1),
-			zap.AddStacktrace(zapcore.ErrorLevel),
-			zap.Fields(
-				fields...,
-			),
-		)
-		return logger, nil
-	} else {
-		encoder := zapcore.NewJSONEncoder(config.EncoderConfig)
-		consoleWriter := zapcore.Lock(os.Stdout)
-		core := zapcore.NewCore(encoder, consoleWriter, config.Level)
-
-		logger := zap.New(
-			core,
-			zap.AddCaller(),
-			zap.AddCallerSkip(1),
-			zap.AddStacktrace(zapcore.ErrorLevel),
-			zap.Fields(
-				fields...,
-			),
-		)
-		return logger, nil
+func WithUptime(uptime bool) Option {
+	return func(l *Logger) {
+		l.uptime = uptime
 	}
 }
 
-func (l *Logger) newZapProduction(fields ...zap.Field) (*zap.Logger, error) {
-	config := zap.NewProductionConfig()
-	config.EncoderConfig.LevelKey = "level"
-	config.EncoderConfig.TimeKey = "time"
-	config.EncoderConfig.MessageKey = "message"
-	config.EncoderConfig.CallerKey = "caller"
-	config.EncoderConfig.StacktraceKey = "stacktrace"
-	// config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	config.EncoderConfig.EncodeTime = formatTime
-	// 应用级别
-	config.Level = zap.NewAtomicLevelAt(l.level)
+func WithSequence(sequence bool) Option {
+	return func(l *Logger) {
+		l.sequence = sequence
+	}
+}
 
-	if !l.logToFile {
-		encoder := zapcore.NewJSONEncoder(config.EncoderConfig)
-		core := zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), config.Level)
-
-		logger := zap.New(
-			core,
-			zap.AddCaller(),
-			zap.AddCallerSkip(1),
-			zap.AddStacktrace(zapcore.ErrorLevel),
-			zap.Fields(
-				fields...,
-			),
-		)
-		return logger, nil
+func WithTimeKey(timeKey string) Option {
+	return func(l *Logger) {
+		l.timeKey = timeKey
 	}
+}
 
-	if l.rotate {
-		logWriter := l.getLogWriter()
-		encoder := zapcore.NewJSONEncoder(config.EncoderConfig)
-		core := zapcore.NewCore(encoder, logWriter, config.Level)
-
-		logger := zap.New(
-			core,
-			zap.AddCaller(),
-			zap.AddCallerSkip(1),
-			zap.AddStacktrace(zapcore.ErrorLevel),
-			zap.Fields(
-				fields...,
-			),
-		)
-		return logger, nil
-	} else {
-		err := checkFile(l.rotatePath)
-		if err != nil {
-			return nil, err
-		}
+func WithEpochKey(epochKey string) Option {
+	return func(l *Logger) {
+		l.epochKey = epochKey
+	}
+}
 
-		file, err := os.OpenFile(l.rotatePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-		if err != nil {
-			return nil, err
-		}
+func WithUTC(utc bool) Option {
+	return func(l *Logger) {
+		l.timeUTC = utc
+	}
+}
 
-		encoder := zapcore.NewJSONEncoder(config.EncoderConfig)
-		core := zapcore.NewCore(encoder, zapcore.AddSync(file), config.Level)
-
-		logger := zap.New(
-			core,
-			zap.AddCaller(),
-			zap.AddCallerSkip(1),
-			zap.AddStacktrace(zapcore.ErrorLevel),
-			zap.Fields(
-				fields...,
-			),
-		)
-		return logger, nil
+// WithTimePrecision 设置时间的精度, 例如 time.Microsecond 或 time.Second, 时间按精度截断并调整小数位数
+// 对 WithTimeLayoutUTCZ 等时间格式同样生效
+func WithTimePrecision(precision time.Duration) Option {
+	return func(l *Logger) {
+		l.timePrecision = precision
 	}
 }
 
-func (l *Logger) getLogWriter() zapcore.WriteSyncer {
-	return zapcore.AddSync(&lumberjack.Logger{
-		Filename:   l.rotatePath,     // 日志文件的位置
-		MaxSize:    l.rotateSize,     // 在进行切割之前, 日志文件的最大大小（以MB为单位）
-		MaxBackups: l.rotateBackups,  // 保留旧文件的最大个数
-		MaxAge:     l.rotateAge,      // 保留旧文件的最大天数
-		Compress:   l.rotateCompress, // 是否压缩/归档旧文件
-	})
+func WithTimeLayoutUTCZ(rfc3339Z bool) Option {
+	return func(l *Logger) {
+		l.timeRFC3339Z = rfc3339Z
+	}
 }
 
-func formatTime(t time.Time, pae zapcore.PrimitiveArrayEncoder) {
-	pae.AppendString(t.Format("2006-01-02 15:04:05.000Z0700"))
+func WithLineEnding(lineEnding string) Option {
+	return func(l *Logger) {
+		l.lineEnding = lineEnding
+	}
+}
+
+func WithDeprecationLimit(deprecationLimit int) Option {
+	return func(l *Logger) {
+		l.deprecationLimit = deprecationLimit
+	}
+}
+
+func WithIDGenerator(idGenerator func() string) Option {
+	return func(l *Logger) {
+		l.idGenerator = idGenerator
+	}
+}
+
+func WithContextStatus(contextStatus bool) Option {
+	return func(l *Logger) {
+		l.contextStatus = contextStatus
+	}
+}
+
+func WithClientLogLevel(clientLogLevel zapcore.Level) Option {
+	return func(l *Logger) {
+		l.clientLogLevel = clientLogLevel
+	}
+}
+
+// WithLazyContextKeys 设置每条日志写入时才从上下文读取的键, 上下文中的值不可变,
+// 因此会变化的值应当以指针或 fmt.Stringer 的形式存入上下文, 在写入时取当前值
+func WithLazyContextKeys(keys ...string) Option {
+	return func(l *Logger) {
+		l.lazyContextKeys = keys
+	}
+}
+
+// WithPprofLabels 在 WithContext 时读取上下文中 pprof.Do 或 pprof.WithLabels 设置的标签, 把指定的键添加为字段
+// Go 不提供读取当前协程标签的接口, 因此标签只能从上下文读取, 没有设置标签时不添加字段
+// 每个键都要遍历一次上下文中的标签, 键较多时会增加 WithContext 的开销
+func WithPprofLabels(keys ...string) Option {
+	return func(l *Logger) {
+		l.pprofLabelKeys = keys
+	}
+}
+
+func WithDebugHeader(debugHeader string) Option {
+	return func(l *Logger) {
+		l.debugHeader = debugHeader
+	}
+}
+
+// WithSamplerFunc 设置自定义采样函数, 它在级别判断通过后对每条日志同步调用,
+// 会直接增加每次记录日志的耗时, 因此应当足够轻量并且可以被并发调用
+func WithSamplerFunc(samplerFunc func(ent zapcore.Entry) zapcore.SamplingDecision) Option {
+	return func(l *Logger) {
+		l.samplerFunc = samplerFunc
+	}
+}
+
+func WithSampling(initial, thereafter int) Option {
+	return func(l *Logger) {
+		l.samplingInitial = initial
+		l.samplingThereafter = thereafter
+	}
+}
+
+func WithSamplingTick(samplingTick time.Duration) Option {
+	return func(l *Logger) {
+		l.samplingTick = samplingTick
+	}
+}
+
+// WithSamplingByKey 按字段 fieldKey 的值分别计数采样, 避免一个高频的值挤占其他值的日志
+// 计数使用固定数量的桶, 内存占用不随字段值的数量增长, 但散列冲突的值会共享计数
+func WithSamplingByKey(fieldKey string, initial, thereafter int) Option {
+	return func(l *Logger) {
+		l.samplingKey = fieldKey
+		l.samplingKeyInitial = initial
+		l.samplingKeyThereafter = thereafter
+	}
+}
+
+// WithSamplingByRequest 按 WithContext 添加的请求ID分别计数采样, 一个日志很多的请求被限流时不影响其他请求
+// 键取自 WithRequestKey 配置, 没有请求ID的日志不参与这项采样; 计数同样使用固定数量的桶,
+// 内存占用与请求数量无关, 但散列冲突的请求会共享计数, 并发请求很多时限流会偏严
+func WithSamplingByRequest(initial, thereafter int) Option {
+	return func(l *Logger) {
+		l.samplingByRequest = true
+		l.samplingKeyInitial = initial
+		l.samplingKeyThereafter = thereafter
+	}
+}
+
+// WithMaxReflectDepth 限制 zap.Any 等通过反射编码的字段的嵌套深度, 默认5, 为 0 时使用 zap 默认的编码器
+// 超过深度的部分输出为 [max depth exceeded], 循环引用输出为 [cycle], 避免误记录庞大的对象图;
+// 没有超过深度时输出与 zap 默认的编码器一致, 但每次编码都要额外遍历一次值
+func WithMaxReflectDepth(maxReflectDepth int) Option {
+	return func(l *Logger) {
+		l.maxReflectDepth = maxReflectDepth
+	}
+}
+
+// WithSamplingExclude 设置不参与采样的日志, fn 返回 true 的日志跳过 WithSampling、WithSamplingByKey 等所有采样
+// 例如按消息前缀匹配审计日志, 保证开启采样时这些日志也不会被丢弃, 跳过的日志不计入采样统计
+func WithSamplingExclude(fn func(ent zapcore.Entry) bool) Option {
+	return func(l *Logger) {
+		l.samplingExclude = fn
+	}
+}
+
+// WithMessagePrefix 在每条日志消息前添加前缀, 例如 "[cache] ", 前缀与消息之间不会自动添加空格
+// 前缀只修改消息, 可以与 Component 同时使用, 不影响 component 字段
+func WithMessagePrefix(prefix string) Option {
+	return func(l *Logger) {
+		l.messagePrefix = prefix
+	}
+}
+
+// WithSanitizeNewlines 把消息和字符串字段中的换行替换为字面的 \n 和 \r, 保证文本格式的每条日志也只占一行
+// JSON 格式本身会转义换行, 主要用于 WithConsoleEncoding 和 WithHumanFile 的输出; 堆栈保持多行不变
+func WithSanitizeNewlines(sanitizeNewlines bool) Option {
+	return func(l *Logger) {
+		l.sanitizeNewlines = sanitizeNewlines
+	}
+}
+
+// WithMaxFieldSize 限制单个字段编码后的最大字节数, 超过时截断并添加截断标记, 为0时不限制
+// 字符串和字节字段直接截断; zap.Any、zap.Strings、数组和对象字段先编码为 JSON, 超过时替换为截断后的 JSON 字符串;
+// 数字等固定长度的字段和错误字段不截断
+func WithMaxFieldSize(maxFieldSize int) Option {
+	return func(l *Logger) {
+		l.maxFieldSize = maxFieldSize
+	}
+}
+
+// WithValidateSchema 只在开发环境生效, 日志缺少 required 中的字段时额外输出一条警告, 生产环境不做任何检查
+func WithValidateSchema(required ...string) Option {
+	return func(l *Logger) {
+		l.requiredFields = required
+	}
+}
+
+func WithRedactFunc(redactFunc func(key string, val interface{}) (interface{}, bool)) Option {
+	return func(l *Logger) {
+		l.redactFunc = redactFunc
+	}
+}
+
+func WithFloatPrecision(floatPrecision int) Option {
+	return func(l *Logger) {
+		l.roundFloats = true
+		l.floatPrecision = floatPrecision
+	}
+}
+
+func WithMessageKey(messageKey string) Option {
+	return func(l *Logger) {
+		l.messageKey = messageKey
+	}
+}
+
+func WithoutMessage() Option {
+	return WithMessageKey("")
+}
+
+func WithStackdriver(stackdriver bool) Option {
+	return func(l *Logger) {
+		l.stackdriver = stackdriver
+	}
+}
+
+func WithCallerEncoder(callerEncoder zapcore.CallerEncoder) Option {
+	return func(l *Logger) {
+		l.callerEncoder = callerEncoder
+	}
+}
+
+func WithShortCaller(shortCaller bool) Option {
+	return func(l *Logger) {
+		if shortCaller {
+			l.callerEncoder = zapcore.ShortCallerEncoder
+		} else {
+			l.callerEncoder = zapcore.FullCallerEncoder
+		}
+	}
+}
+
+func WithCallerTrimPrefix(callerTrimPrefix string) Option {
+	return func(l *Logger) {
+		l.callerTrimPrefix = callerTrimPrefix
+	}
+}
+
+func WithDowngradeErrors(downgradeErrors func(error) zapcore.Level) Option {
+	return func(l *Logger) {
+		l.downgradeErrors = downgradeErrors
+	}
+}
+
+func WithNoStacktraceFor(noStacktraceFor func(error) bool) Option {
+	return func(l *Logger) {
+		l.noStacktraceFor = noStacktraceFor
+	}
+}
+
+func NewDevelopment() (*Logger, error) {
+	return New(
+		WithEnv(Development),
+		WithLevel(zapcore.DebugLevel),
+		WithServiceName(ServerName),
+		WithVersionName(Version),
+		WithRequestKey(RequestKey),
+		WithUserKey(UserKey),
+	)
+}
+
+func NewProduction() (*Logger, error) {
+	return New(
+		WithEnv(Production),
+		WithLevel(zapcore.InfoLevel),
+		WithServiceName(ServerName),
+		WithVersionName(Version),
+		WithRequestKey(RequestKey),
+		WithUserKey(UserKey),
+		WithRotate(true),
+		WithRotatePath("logs/run.log"),
+		WithRotateSize(10),
+		WithRotateAge(30),
+		WithRotateBackups(30),
+		WithRotateCompress(false),
+	)
+}
+
+// NewProductionMinimal 创建面向高吞吐服务的生产环境日志实例, 以 JSON 格式输出到标准输出,
+// 不输出调用位置, 只在 Panic 及以上级别输出堆栈, 并开启采样以降低每条日志的开销
+func NewProductionMinimal() (*Logger, error) {
+	return New(
+		WithEnv(Production),
+		WithLevel(zapcore.InfoLevel),
+		WithServiceName(ServerName),
+		WithVersionName(Version),
+		WithRequestKey(RequestKey),
+		WithUserKey(UserKey),
+		WithCaller(false),
+		WithStacktraceLevel(zapcore.PanicLevel),
+		WithSampling(100, 100),
+	)
+}
+
+// NewDevFile 创建开发环境的日志实例, 以易读的文本格式输出到标准输出, 同时以 JSON 格式写入分割的日志文件
+func NewDevFile(path string) (*Logger, error) {
+	return New(
+		WithEnv(Development),
+		WithLevel(zapcore.DebugLevel),
+		WithServiceName(ServerName),
+		WithVersionName(Version),
+		WithRequestKey(RequestKey),
+		WithUserKey(UserKey),
+		WithConsoleEncoding(true),
+		WithLogToFile(true),
+		WithRotate(true),
+		WithRotatePath(path),
+	)
+}
+
+// NewAudit 创建审计日志实例, 以 JSON 格式写入单独的日志文件 path, 不输出到标准输出
+// 每条日志写入后立即 fsync, 并且不参与任何采样, 以吞吐量为代价保证日志不丢失; 文件不会自动轮转
+func NewAudit(path string) (*Logger, error) {
+	return New(
+		WithEnv(Production),
+		WithLevel(zapcore.InfoLevel),
+		WithServiceName(ServerName),
+		WithVersionName(Version),
+		WithRequestKey(RequestKey),
+		WithUserKey(UserKey),
+		WithLogToFile(true),
+		WithRotate(false),
+		WithRotatePath(path),
+		func(l *Logger) {
+			l.audit = true
+		},
+	)
+}
+
+func New(opts ...Option) (*Logger, error) {
+	l := &Logger{config: &config{
+		env:              Development,
+		level:            zapcore.DebugLevel,
+		serviceName:      ServerName,
+		versionName:      Version,
+		requestKey:       RequestKey,
+		userKey:          UserKey,
+		logToFile:        false,
+		rotate:           false,
+		rotatePath:       "logs/run.log",
+		rotateSize:       10,
+		rotateAge:        7,
+		rotateBackups:    10,
+		rotateCompress:   false,
+		deprecationLimit: 3,
+		messageKey:       "message",
+		timeKey:          "time",
+		caller:           true,
+		stacktraceLevel:  zapcore.ErrorLevel,
+		consoleLevel:     zapcore.DebugLevel,
+		fileLevel:        zapcore.DebugLevel,
+		sinkWriteTimeout: time.Second,
+		maxReflectDepth:  defaultMaxReflectDepth,
+	}}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l.newZap()
+}
+
+// Must 在创建日志实例失败时 panic, 例如：logger.Must(logger.NewProduction())
+func Must(l *Logger, err error) *Logger {
+	if err != nil {
+		panic("logger: create logger failed: " + err.Error())
+	}
+	return l
+}
+
+func (l *Logger) With(fields ...zap.Field) *Logger {
+	return l.withZap(l.zap.With(fields...))
+}
+
+// Component 返回带有 component 字段的子日志实例, 用于区分服务内的各个组件
+// 已经通过 WithComponent 或 Component 设置了组件名时替换原来的组件名, 日志中只有一个 component 字段
+func (l *Logger) Component(name string) *Logger {
+	found := false
+	z := l.zap.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		core, found = setComponent(core, name)
+		return core
+	}))
+	// WithZapOptions 包装的 core 位于外层时找不到 componentCore, 退回为添加字段
+	if !found {
+		z = l.zap.With(zap.String("component", name))
+	}
+	child := l.withZap(z)
+	child.component = name
+	return child
+}
+
+func (l *Logger) WithIf(cond bool, fields ...zap.Field) *Logger {
+	if !cond {
+		return l
+	}
+	return l.With(fields...)
+}
+
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	newLogger := l.zap
+
+	if requestID, ok := ctx.Value(l.requestKey).(string); ok {
+		newLogger = newLogger.With(zap.String(l.requestKey, requestID))
+	}
+
+	if userID, ok := ctx.Value(l.userKey).(string); ok {
+		newLogger = newLogger.With(zap.String(l.userKey, userID))
+	}
+
+	if l.contextStatus {
+		if err := ctx.Err(); err != nil {
+			newLogger = newLogger.With(zap.String("ctx_err", err.Error()))
+		}
+	}
+
+	for _, key := range l.pprofLabelKeys {
+		if val, ok := pprof.Label(ctx, key); ok {
+			newLogger = newLogger.With(zap.String(key, val))
+		}
+	}
+
+	if len(l.lazyContextKeys) > 0 {
+		newLogger = newLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return wrapUnderLevel(core, func(core zapcore.Core) zapcore.Core {
+				return &lazyContextCore{Core: core, ctx: ctx, keys: l.lazyContextKeys}
+			})
+		}))
+	}
+
+	return l.withZap(newLogger)
+}
+
+// Detach 返回一个保留当前所有字段、但不再引用上下文的日志实例, 用于在请求结束后仍会运行的协程中记录日志
+// With 和 WithContext 添加的字段在添加时已经固定, WithLazyContextKeys 的值在调用 Detach 时读取并固定
+func (l *Logger) Detach() *Logger {
+	return l.withZap(l.zap.WithOptions(zap.WrapCore(detachCore)))
+}
+
+// Once 返回一个按 key 去重的日志实例, 同一个 key 在进程生命周期内只输出第一条日志
+func (l *Logger) Once(key string) *Logger {
+	return l.withZap(l.zap.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return wrapUnderLevel(core, func(core zapcore.Core) zapcore.Core {
+			return newEntryCore(core, func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+				_, loaded := onceKeys.LoadOrStore(key, struct{}{})
+				return ent, fields, !loaded
+			})
+		})
+	})))
+}
+
+// AtLevel 返回一个使用指定级别的日志实例, 不影响原实例的级别, 可以用来为单个请求开启调试日志
+func (l *Logger) AtLevel(level zapcore.Level) *Logger {
+	return l.withZap(l.zap.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return atLevel(core, level)
+	})))
+}
+
+// Deprecated 输出废弃特性的警告, 每个特性最多输出 deprecationLimit 次, 之后不再输出
+func (l *Logger) Deprecated(feature string) {
+	counter, _ := deprecatedCounts.LoadOrStore(feature, new(atomic.Int64))
+	if counter.(*atomic.Int64).Add(1) > int64(l.deprecationLimit) {
+		return
+	}
+
+	fields := []zap.Field{zap.String("feature", feature)}
+	// 记录调用废弃特性的位置
+	if pc, file, line, ok := runtime.Caller(2); ok {
+		fields = append(fields, zap.String("called_from", zapcore.NewEntryCaller(pc, file, line, ok).TrimmedPath()))
+	}
+	l.zap.Warn("Deprecated feature used", fields...)
+}
+
+// WithLevelDuring 使用指定级别的子日志实例执行 fn, 只影响 fn 内通过参数记录的日志, 不修改当前实例
+func (l *Logger) WithLevelDuring(level zapcore.Level, fn func(*Logger)) {
+	fn(l.AtLevel(level))
+}
+
+// Tee 返回同时写入当前实例和 other 的日志实例, 两者各自保留自己的级别和字段, 之后调用 AtLevel 会同时调整两者的级别
+func (l *Logger) Tee(other *Logger) *Logger {
+	return l.withZap(l.zap.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		// 外层的 levelCore 取两者级别的并集
+		tee := teeCore{core, other.zap.Core()}
+		return &levelCore{Core: tee, enab: tee}
+	})))
+}
+
+func (l *Logger) Debug(msg string, fields ...zap.Field) {
+	l.zap.Debug(msg, fields...)
+}
+
+func (l *Logger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	l.WithContext(ctx).zap.Debug(msg, fields...)
+}
+
+func (l *Logger) Info(msg string, fields ...zap.Field) {
+	l.zap.Info(msg, fields...)
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	l.WithContext(ctx).zap.Info(msg, fields...)
+}
+
+func (l *Logger) Warn(msg string, fields ...zap.Field) {
+	l.zap.Warn(msg, fields...)
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	l.WithContext(ctx).zap.Warn(msg, fields...)
+}
+
+func (l *Logger) Error(msg string, err error, fields ...zap.Field) {
+	if err == nil {
+		l.zap.Error(msg, fields...)
+		return
+	}
+	buf := errorFields(fields, err)
+	l.errorZap(l.zap, err).Log(l.errorLevel(err), msg, *buf...)
+	putFields(buf)
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, msg string, err error, fields ...zap.Field) {
+	if err == nil {
+		l.WithContext(ctx).zap.Error(msg, fields...)
+		return
+	}
+	buf := errorFields(fields, err)
+	l.errorZap(l.WithContext(ctx).zap, err).Log(l.errorLevel(err), msg, *buf...)
+	putFields(buf)
+}
+
+// errorZap 返回记录该错误使用的 zap 实例, 错误匹配 noStacktraceFor 时不输出堆栈
+func (l *Logger) errorZap(zapLogger *zap.Logger, err error) *zap.Logger {
+	if l.noStacktraceFor == nil || !l.noStacktraceFor(err) {
+		return zapLogger
+	}
+	return zapLogger.WithOptions(zap.AddStacktrace(zapcore.InvalidLevel))
+}
+
+// errorLevel 返回记录该错误使用的级别, 默认是 Error
+func (l *Logger) errorLevel(err error) zapcore.Level {
+	if l.downgradeErrors == nil {
+		return zapcore.ErrorLevel
+	}
+	return l.downgradeErrors(err)
+}
+
+// DPanic 输出 DPanic 级别的日志, 开发模式下输出后 panic, 见 WithDevelopment
+func (l *Logger) DPanic(msg string, fields ...zap.Field) {
+	l.zap.DPanic(msg, fields...)
+}
+
+func (l *Logger) DPanicCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	l.WithContext(ctx).zap.DPanic(msg, fields...)
+}
+
+func (l *Logger) Fatal(msg string, fields ...zap.Field) {
+	l.zap.Fatal(msg, fields...)
+}
+
+func (l *Logger) FatalCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	l.WithContext(ctx).zap.Fatal(msg, fields...)
+}
+
+func (l *Logger) Trace(ctx context.Context, funcName string) func() {
+	logger := l.WithContext(ctx)
+
+	startTime := time.Now()
+	logger.Debug("Starting function", zap.String("function", funcName))
+
+	return func() {
+		duration := time.Since(startTime)
+		fields := []zap.Field{
+			zap.String("function", funcName),
+			zap.Duration("duration", duration),
+		}
+		logger.Debug("Finished function", append(fields, deadlineFields(ctx)...)...)
+	}
+}
+
+// TraceMem 与 Trace 相同, 同时在结束日志中记录执行期间的内存分配字节数和分配次数
+// runtime.ReadMemStats 会短暂暂停所有协程, 因此只在开启 WithTraceMemSampling 且被抽中时读取, 否则等同于 Trace;
+// 统计的是整个进程的分配, 并发执行的其他协程的分配也会计入
+func (l *Logger) TraceMem(ctx context.Context, funcName string) func() {
+	if l.traceMemRate <= 0 || !l.zap.Core().Enabled(zapcore.DebugLevel) || rand.Float64() >= l.traceMemRate {
+		return l.Trace(ctx, funcName)
+	}
+
+	logger := l.WithContext(ctx)
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	startTime := time.Now()
+	logger.Debug("Starting function", zap.String("function", funcName))
+
+	return func() {
+		duration := time.Since(startTime)
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+
+		fields := []zap.Field{
+			zap.String("function", funcName),
+			zap.Duration("duration", duration),
+			zap.Uint64("alloc_bytes", after.TotalAlloc-before.TotalAlloc),
+			zap.Uint64("mallocs", after.Mallocs-before.Mallocs),
+		}
+		logger.Debug("Finished function", append(fields, deadlineFields(ctx)...)...)
+	}
+}
+
+// deadlineFields 返回 ctx 期限相关的字段, 结束时已超过期限 deadline_remaining 为负数, ctx 没有期限时返回 nil
+func deadlineFields(ctx context.Context) []zap.Field {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	remaining := time.Until(deadline)
+	return []zap.Field{
+		zap.Bool("deadline_exceeded", remaining <= 0),
+		zap.Duration("deadline_remaining", remaining),
+	}
+}
+
+func (l *Logger) Sync() error {
+	return l.zap.Sync()
+}
+
+// Rotate 刷新缓冲后立即轮转日志文件, 不必等待达到大小或时间限制, 未开启轮转时什么也不做
+func (l *Logger) Rotate() error {
+	if len(l.rotators) == 0 {
+		return nil
+	}
+	_ = l.zap.Sync()
+
+	var errs []error
+	for _, rotator := range l.rotators {
+		if err := rotator.Rotate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// withZap 基于当前配置创建一个使用新 zap 实例的日志实例
+func (l *Logger) withZap(zapLogger *zap.Logger) *Logger {
+	clone := *l
+	clone.zap = zapLogger
+	return &clone
+}
+
+// Clone 复制当前实例的配置并应用 opts, 重新创建一个独立的日志实例, 不影响当前实例及其打开的文件
+// 只复制配置, 不包含通过 With 等方法添加的字段, 采样计数等运行状态也会重新开始
+func (l *Logger) Clone(opts ...Option) (*Logger, error) {
+	cfg := *l.config
+	clone := &Logger{config: &cfg, component: l.component}
+	clone.closers = nil
+	clone.rotators = nil
+	clone.sampler = nil
+	clone.keySampler = nil
+	clone.samplingStats = nil
+	clone.networkStats = nil
+	// 追加类的选项不能写入原实例的切片
+	clone.networkSinks = slices.Clip(l.networkSinks)
+	clone.writers = slices.Clip(l.writers)
+	clone.extraZapOptions = slices.Clip(l.extraZapOptions)
+
+	for _, opt := range opts {
+		opt(clone)
+	}
+
+	return clone.newZap()
+}
+
+func (l *Logger) newZap() (*Logger, error) {
+	if l.optionErr != nil {
+		return nil, l.optionErr
+	}
+
+	zapFields := []zap.Field{
+		zap.String("env", l.env),
+	}
+	if l.serviceName != "" {
+		zapFields = append(zapFields, zap.String("service", l.serviceName))
+	}
+	if l.versionName != "" {
+		zapFields = append(zapFields, zap.String("version", l.versionName))
+	}
+	if l.schemaVersion != "" {
+		zapFields = append(zapFields, zap.String("schema", l.schemaVersion))
+	}
+	if l.buildInfo {
+		zapFields = append(zapFields, buildInfoFields()...)
+	}
+	if l.kubernetesFields {
+		zapFields = append(zapFields, l.kubernetesEnvFields()...)
+	}
+
+	l.bytesWritten = &atomic.Uint64{}
+	l.levelStats = &levelStats{}
+	l.closeGuard = &closeGuard{}
+
+	var (
+		config zap.Config
+		core   zapcore.Core
+		err    error
+	)
+	switch l.env {
+	case Development:
+		config = l.newConfig(zap.NewDevelopmentConfig())
+		core, err = l.newDevelopmentCore(config)
+	case Production:
+		config = l.newConfig(zap.NewProductionConfig())
+		core, err = l.newProductionCore(config)
+	default:
+		return nil, errors.New("invalid environment,  use development or production")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	core, err = l.teeCores(core, config)
+	if err != nil {
+		return nil, err
+	}
+
+	core = &levelCore{Core: l.wrapCore(core, config.EncoderConfig), enab: zap.NewAtomicLevelAt(l.level)}
+	l.zap = zap.New(core, l.zapOptions(zapFields...)...)
+	if l.syncInterval > 0 {
+		l.startPeriodicSync(core)
+	}
+	if l.startupLog {
+		l.zap.Info("Logger initialized", l.startupFields()...)
+	}
+	return l, nil
+}
+
+// startupFields 返回启动日志的字段, 描述实际生效的配置, env 已经是基础字段
+func (l *Logger) startupFields() []zap.Field {
+	fields := []zap.Field{
+		zap.String("log_level", l.level.String()),
+		zap.Strings("outputs", l.outputNames()),
+	}
+	if l.logToFile && l.rotate {
+		fields = append(fields,
+			zap.String("rotate_path", l.rotatePath),
+			zap.Int("rotate_max_size_mb", l.rotateSize),
+			zap.Int("rotate_max_age_days", l.rotateAge),
+			zap.Int("rotate_max_backups", l.rotateBackups),
+			zap.Bool("rotate_compress", l.rotateCompress),
+		)
+	}
+	return fields
+}
+
+// outputNames 返回实际写入的输出目标, 与 newDevelopmentCore、newProductionCore 和 teeCores 的逻辑一致
+func (l *Logger) outputNames() []string {
+	var outputs []string
+	switch {
+	case !l.logToFile:
+		outputs = append(outputs, "stdout")
+	case l.rotate && l.env == Development:
+		outputs = append(outputs, "file:"+l.rotatePath, "stdout")
+	case l.rotate, l.env == Production:
+		outputs = append(outputs, "file:"+l.rotatePath)
+	default:
+		outputs = append(outputs, "stdout")
+	}
+	if l.eventLogSource != "" {
+		outputs = append(outputs, "eventlog:"+l.eventLogSource)
+	}
+	if l.journald && isExist(journalSocket) {
+		outputs = append(outputs, "journald")
+	}
+	if l.humanFile != "" {
+		outputs = append(outputs, "file:"+l.humanFile)
+	}
+	for range l.writers {
+		outputs = append(outputs, "writer")
+	}
+	if l.crashFile != "" {
+		outputs = append(outputs, "crash:"+l.crashFile)
+	}
+	if l.unixSocket != "" {
+		outputs = append(outputs, "unix:"+l.unixSocket)
+	}
+	for range l.networkSinks {
+		outputs = append(outputs, "network")
+	}
+	return outputs
+}
+
+// wrapCore 按配置为 core 添加逐条处理日志的包装
+func (l *Logger) wrapCore(core zapcore.Core, encoderConfig zapcore.EncoderConfig) zapcore.Core {
+	// 计数放在最内层, 只统计经过采样、去重等处理后实际写出的日志
+	core = newEntryCore(core, l.levelStats.count)
+	// 字段过滤放在最内层, 其他包装添加的字段同样会被过滤
+	if len(l.includeFields) > 0 || len(l.excludeFields) > 0 {
+		core = newFieldCore(core, l.filterField)
+	}
+	if l.reservedKeyCheck {
+		core = newFieldCore(core, l.reservedKeyHook(encoderConfig))
+	}
+	// 放在内层, 外层的处理仍然可以使用 caller, 例如 WithCallerPackage
+	if l.callerLevel != nil {
+		core = newEntryCore(core, func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+			if !l.callerLevel.Enabled(ent.Level) {
+				ent.Caller = zapcore.EntryCaller{}
+			}
+			return ent, fields, true
+		})
+	}
+	if l.env == Development && len(l.requiredFields) > 0 {
+		core = newSchemaCore(core, l.requiredFields)
+	}
+	if l.redactFunc != nil {
+		core = newFieldCore(core, l.redactField)
+	}
+	if l.roundFloats {
+		core = newFieldCore(core, l.roundFloatField)
+	}
+	if l.maxFieldSize > 0 {
+		core = newFieldCore(core, l.truncateField)
+	}
+	// 放在截断的外层, 截断的是十六进制字符串而不是 base64
+	if l.hexBinary {
+		core = newFieldCore(core, hexBinaryField)
+	}
+	if l.messagePrefix != "" {
+		core = newEntryCore(core, func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+			ent.Message = l.messagePrefix + ent.Message
+			return ent, fields, true
+		})
+	}
+	if l.sanitizeNewlines {
+		core = newFieldCore(core, sanitizeNewlinesField)
+		core = newEntryCore(core, func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+			ent.Message = newlineReplacer.Replace(ent.Message)
+			return ent, fields, true
+		})
+	}
+	if l.stackdriver {
+		core = newEntryCore(core, stackdriverHook)
+	}
+	if l.uptime {
+		startTime := time.Now()
+		core = newEntryCore(core, func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+			return ent, appendFields(fields, zap.Float64("uptime", time.Since(startTime).Seconds())), true
+		})
+	}
+	if l.callerPackage {
+		core = newEntryCore(core, callerPackageHook)
+	}
+	if l.epochKey != "" {
+		core = newEntryCore(core, func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+			epoch := float64(ent.Time.UnixNano()) / float64(time.Second)
+			return ent, appendFields(fields, zap.Float64(l.epochKey, epoch)), true
+		})
+	}
+	if l.sequence {
+		var seq atomic.Uint64
+		core = newEntryCore(core, func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+			return ent, appendFields(fields, zap.Uint64("seq", seq.Add(1))), true
+		})
+	}
+	// 采样放在最外层, 被丢弃的日志不再经过其他处理
+	// 审计日志不允许被采样丢弃
+	if !l.audit {
+		samplingKey := l.samplingKey
+		if l.samplingByRequest {
+			samplingKey = l.requestKey
+		}
+		if l.samplerFunc != nil || l.samplingInitial > 0 || samplingKey != "" {
+			l.samplingStats = &samplingStats{}
+		}
+		if samplingKey != "" {
+			l.keySampler = newCountSampler(l.samplingKeyInitial, l.samplingKeyThereafter, l.samplingTick)
+			core = &keySamplerCore{Core: core, key: samplingKey, sampler: l.keySampler, exclude: l.samplingExclude, stats: l.samplingStats}
+		}
+		if l.samplingInitial > 0 {
+			l.sampler = newCountSampler(l.samplingInitial, l.samplingThereafter, l.samplingTick)
+			core = &samplerCore{Core: core, sampler: l.sampler, exclude: l.samplingExclude, stats: l.samplingStats}
+		}
+		if l.samplerFunc != nil {
+			core = &samplerFuncCore{Core: core, decide: l.samplerFunc, exclude: l.samplingExclude, stats: l.samplingStats}
+		}
+	}
+	// 告警放在采样之外, 被采样丢弃的日志同样会触发告警
+	if l.alertFunc != nil {
+		core = newEntryCore(core, l.alertHook())
+	}
+	if l.collapseRepeats {
+		core = newCollapseCore(core)
+	}
+	// 去重需要在最外层保存 With 的字段, 否则字段已经被内部的编码器编码
+	if l.dedupeFields {
+		core = &dedupeCore{Core: core}
+	}
+	// 放在其他处理的外层, 关闭后的日志不再经过采样等处理
+	core = &closeGuardCore{Core: core, guard: l.closeGuard}
+	// 组件名在写入时添加, Component 可以直接替换
+	return &componentCore{Core: core, name: l.component}
+}
+
+// newConfig 在环境默认配置的基础上应用统一的编码设置
+func (l *Logger) newConfig(config zap.Config) zap.Config {
+	config.EncoderConfig.LevelKey = "level"
+	config.EncoderConfig.TimeKey = l.timeKey
+	config.EncoderConfig.MessageKey = l.messageKey
+	config.EncoderConfig.CallerKey = "caller"
+	config.EncoderConfig.StacktraceKey = "stacktrace"
+	// config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	if l.lowercaseLevel != nil {
+		if *l.lowercaseLevel {
+			config.EncoderConfig.EncodeLevel = zapcore.LowercaseLevelEncoder
+		} else {
+			config.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		}
+	}
+	config.EncoderConfig.EncodeTime = l.timeEncoder()
+	if l.lineEnding != "" {
+		config.EncoderConfig.LineEnding = l.lineEnding
+	}
+	if l.maxReflectDepth > 0 {
+		config.EncoderConfig.NewReflectedEncoder = newDepthReflectedEncoder(l.maxReflectDepth)
+	}
+	if l.callerEncoder != nil {
+		config.EncoderConfig.EncodeCaller = l.callerEncoder
+	}
+	if l.callerTrimPrefix != "" {
+		config.EncoderConfig.EncodeCaller = trimCallerEncoder(l.callerTrimPrefix)
+	}
+	if l.stackdriver {
+		// 调用位置改由 sourceLocation 字段输出, 时间使用 Cloud Logging 可以解析的 RFC3339 格式
+		config.EncoderConfig.LevelKey = "severity"
+		config.EncoderConfig.EncodeLevel = stackdriverLevelEncoder
+		config.EncoderConfig.CallerKey = ""
+		config.EncoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	}
+	// 各个输出不做级别限制, 应用级别由最外层的 levelCore 统一过滤
+	config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	return config
+}
+
+// zapOptions 构建传给 zap.New 的选项
+func (l *Logger) zapOptions(fields ...zap.Field) []zap.Option {
+	opts := []zap.Option{
+		zap.WithCaller(l.caller),
+		zap.AddCallerSkip(1),
+		zap.AddStacktrace(l.stacktraceLevel),
+		zap.Fields(
+			fields...,
+		),
+	}
+	if l.isDevelopment() {
+		opts = append(opts, zap.Development())
+	}
+	if l.errorOutput != nil {
+		opts = append(opts, zap.ErrorOutput(l.errorOutput))
+	}
+	if l.onFatal != nil || l.panicOnFatal || l.exitFunc != nil {
+		opts = append(opts, zap.WithFatalHook(fatalHook{onFatal: l.onFatal, panic: l.panicOnFatal, exit: l.exitFunc}))
+	}
+	// 自定义的选项放在最后, 可以覆盖上面的默认选项
+	return append(opts, l.extraZapOptions...)
+}
+
+// isDevelopment 是否使用 zap 的开发模式, 未通过 WithDevelopment 设置时跟随 env
+func (l *Logger) isDevelopment() bool {
+	if l.development != nil {
+		return *l.development
+	}
+	return l.env == Development
+}
+
+// fatalHook 在 Fatal 日志写入后执行清理回调, 然后退出进程或 panic
+type fatalHook struct {
+	onFatal func()
+	panic   bool
+	exit    func(int)
+}
+
+func (h fatalHook) OnWrite(ce *zapcore.CheckedEntry, _ []zapcore.Field) {
+	if h.onFatal != nil {
+		h.onFatal()
+	}
+	if h.panic {
+		panic(ce.Message)
+	}
+	if h.exit != nil {
+		h.exit(1)
+		return
+	}
+	os.Exit(1)
+}
+
+// teeCores 把额外的输出目标与主 core 合并
+func (l *Logger) teeCores(core zapcore.Core, config zap.Config) (zapcore.Core, error) {
+	cores := []zapcore.Core{core}
+
+	if l.eventLogSource != "" {
+		encoder := zapcore.NewJSONEncoder(config.EncoderConfig)
+		eventLogCore, err := l.newEventLogCore(encoder, config.Level)
+		if err != nil {
+			return nil, err
+		}
+		cores = append(cores, eventLogCore)
+	}
+
+	if l.journald {
+		if journalCore := l.newJournalCore(config.Level); journalCore != nil {
+			cores = append(cores, journalCore)
+		}
+	}
+
+	if l.humanFile != "" {
+		encoder := zapcore.NewConsoleEncoder(config.EncoderConfig)
+		cores = append(cores, zapcore.NewCore(encoder, l.countWriter(l.newRotateWriter(l.humanFile)), l.fileLevel))
+	}
+
+	for _, sink := range l.writers {
+		cores = append(cores, l.newWriterCore(config.EncoderConfig, config.Level, sink))
+	}
+
+	if l.crashFile != "" {
+		crashCore, err := l.newCrashFileCore(config.EncoderConfig)
+		if err != nil {
+			return nil, err
+		}
+		cores = append(cores, crashCore)
+	}
+
+	if l.unixSocket != "" {
+		cores = append(cores, l.newNetworkCore(config.EncoderConfig, config.Level, l.unixSocketSink()))
+	}
+
+	for _, sink := range l.networkSinks {
+		cores = append(cores, l.newNetworkCore(config.EncoderConfig, config.Level, sink))
+	}
+
+	if len(cores) == 1 {
+		return core, nil
+	}
+	return zapcore.NewTee(cores...), nil
+}
+
+func (l *Logger) newDevelopmentCore(config zap.Config) (zapcore.Core, error) {
+	if !l.logToFile {
+		return l.newStdoutCore(l.newConsoleEncoder(config), true), nil
+	}
+
+	if l.rotate {
+		logWriter := l.getLogWriter()
+		encoder := zapcore.NewJSONEncoder(config.EncoderConfig)
+		fileCore := zapcore.NewCore(encoder, l.countWriter(l.auditWriter(logWriter)), l.fileLevel)
+
+		consoleCore := l.newStdoutCore(l.newConsoleEncoder(config), true)
+		return zapcore.NewTee(fileCore, consoleCore), nil
+	} else {
+		return l.newStdoutCore(l.newConsoleEncoder(config), true), nil
+	}
+}
+
+// newStdoutCore 创建输出到终端的 core, 开启 WithSplitStreams 时 Error 及以上级别写入标准错误
+// pretty 为 true 时按 WithPrettyJSON 的配置格式化输出
+func (l *Logger) newStdoutCore(encoder zapcore.Encoder, pretty bool) zapcore.Core {
+	writer := func(file *os.File) zapcore.WriteSyncer {
+		ws := l.countWriter(zapcore.Lock(file))
+		if pretty {
+			ws = l.prettyWriter(ws)
+		}
+		return ws
+	}
+	if !l.splitStreams {
+		return zapcore.NewCore(encoder, writer(os.Stdout), l.consoleLevel)
+	}
+
+	stdoutLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		return lvl >= l.consoleLevel && lvl < zapcore.ErrorLevel
+	})
+	stderrLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		return lvl >= l.consoleLevel && lvl >= zapcore.ErrorLevel
+	})
+	return zapcore.NewTee(
+		zapcore.NewCore(encoder.Clone(), writer(os.Stdout), stdoutLevel),
+		zapcore.NewCore(encoder, writer(os.Stderr), stderrLevel),
+	)
+}
+
+func (l *Logger) newProductionCore(config zap.Config) (zapcore.Core, error) {
+	if !l.logToFile {
+		return l.newStdoutCore(l.newConsoleEncoder(config), false), nil
+	}
+
+	if l.rotate {
+		logWriter := l.getLogWriter()
+		encoder := zapcore.NewJSONEncoder(config.EncoderConfig)
+		return zapcore.NewCore(encoder, l.countWriter(l.auditWriter(logWriter)), l.fileLevel), nil
+	} else {
+		err := checkFile(l.rotatePath)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := os.OpenFile(l.rotatePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		l.addCloser(file.Close)
+
+		encoder := zapcore.NewJSONEncoder(config.EncoderConfig)
+		return zapcore.NewCore(encoder, l.countWriter(l.auditWriter(zapcore.AddSync(file))), l.fileLevel), nil
+	}
+}
+
+// auditWriter 审计模式下每次写入日志文件后立即 fsync
+func (l *Logger) auditWriter(ws zapcore.WriteSyncer) zapcore.WriteSyncer {
+	if !l.audit {
+		return ws
+	}
+	return &fsyncWriter{WriteSyncer: ws}
+}
+
+// prettyWriter 开启 prettyJSON 且标准输出使用 JSON 时, 把每条日志缩进后再写入
+func (l *Logger) prettyWriter(ws zapcore.WriteSyncer) zapcore.WriteSyncer {
+	if !l.prettyJSON || l.consoleEncoding {
+		return ws
+	}
+	return &indentWriter{WriteSyncer: ws}
+}
+
+// newWriterCore 创建写入 WithWriter/AddWriter 添加的输出目标的 core, 未指定的级别和编码器使用默认值
+func (l *Logger) newWriterCore(encoderConfig zapcore.EncoderConfig, enab zapcore.LevelEnabler, sink writerSink) zapcore.Core {
+	encoder := sink.encoder
+	if encoder == nil {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+	if sink.enab != nil {
+		enab = sink.enab
+	}
+	return zapcore.NewCore(encoder, l.countWriter(zapcore.Lock(zapcore.AddSync(sink.w))), enab)
+}
+
+// newCrashFileCore 创建只记录 Panic 和 Fatal 日志的 core, 文件以追加方式打开且不会轮转
+// zap 写入 Error 以上级别的日志后会立即 Sync, 因此退出前日志已经落盘
+func (l *Logger) newCrashFileCore(encoderConfig zapcore.EncoderConfig) (zapcore.Core, error) {
+	if err := checkFile(l.crashFile); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(l.crashFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l.addCloser(file.Close)
+
+	encoder := zapcore.NewJSONEncoder(encoderConfig)
+	return zapcore.NewCore(encoder, l.countWriter(zapcore.AddSync(file)), zapcore.PanicLevel), nil
+}
+
+// countWriter 包装输出目标, 统计写入的字节数
+func (l *Logger) countWriter(ws zapcore.WriteSyncer) zapcore.WriteSyncer {
+	return &countingWriter{WriteSyncer: ws, n: l.bytesWritten}
+}
+
+// BytesWritten 返回所有输出目标累计写入的字节数, 同时写入多个输出时按每个输出分别累计
+// journald 和 Windows 事件日志不经过 io.Writer, 不计入统计
+func (l *Logger) BytesWritten() uint64 {
+	if l.bytesWritten == nil {
+		return 0
+	}
+	return l.bytesWritten.Load()
+}
+
+// newConsoleEncoder 创建标准输出使用的编码器, 开启 consoleEncoding 时使用易读的文本格式
+func (l *Logger) newConsoleEncoder(config zap.Config) zapcore.Encoder {
+	if l.consoleEncoding {
+		return zapcore.NewConsoleEncoder(config.EncoderConfig)
+	}
+	return zapcore.NewJSONEncoder(config.EncoderConfig)
+}
+
+// alertHook 返回触发告警回调的 hook, 同一个实例派生出的日志共享冷却时间
+func (l *Logger) alertHook() entryHook {
+	var lastAlert atomic.Int64
+	return func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+		if ent.Level < l.alertLevel {
+			return ent, fields, true
+		}
+		last := lastAlert.Load()
+		now := ent.Time.UnixNano()
+		if last != 0 && now-last < int64(l.alertCooldown) {
+			return ent, fields, true
+		}
+		if lastAlert.CompareAndSwap(last, now) {
+			// 字段切片可能被调用方复用, 交给协程前需要复制
+			go l.alertFunc(ent, append([]zapcore.Field(nil), fields...))
+		}
+		return ent, fields, true
+	}
+}
+
+// callerPackageHook 根据调用方的函数名添加 package 字段
+func callerPackageHook(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+	if !ent.Caller.Defined {
+		return ent, fields, true
+	}
+	function := ent.Caller.Function
+	if function == "" {
+		if fn := runtime.FuncForPC(ent.Caller.PC); fn != nil {
+			function = fn.Name()
+		}
+	}
+	if pkg := packageName(function); pkg != "" {
+		fields = appendFields(fields, zap.String("package", pkg))
+	}
+	return ent, fields, true
+}
+
+// packageName 从完整的函数名中取出包路径, 例如 github.com/a/b.(*T).M 返回 github.com/a/b
+func packageName(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	if dot := strings.IndexByte(function[slash+1:], '.'); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+// reservedFieldPrefix 生产模式下与保留键冲突的字段添加的前缀
+const reservedFieldPrefix = "field_"
+
+// reservedKeyHook 返回检查保留键的 hook, 保留键取自实际使用的编码配置
+func (l *Logger) reservedKeyHook(encoderConfig zapcore.EncoderConfig) fieldHook {
+	reserved := keySet(slices.DeleteFunc([]string{
+		encoderConfig.LevelKey,
+		encoderConfig.TimeKey,
+		encoderConfig.MessageKey,
+		encoderConfig.CallerKey,
+		encoderConfig.StacktraceKey,
+	}, func(key string) bool { return key == "" }))
+	development := l.isDevelopment()
+
+	return func(field zapcore.Field) (zapcore.Field, bool) {
+		if _, ok := reserved[field.Key]; !ok {
+			return field, true
+		}
+		if development {
+			panic(fmt.Sprintf("logger: field key %q is reserved", field.Key))
+		}
+		field.Key = reservedFieldPrefix + field.Key
+		return field, true
+	}
+}
+
+// filterField 按 includeFields 和 excludeFields 决定是否保留字段
+func (l *Logger) filterField(field zapcore.Field) (zapcore.Field, bool) {
+	if _, ok := l.excludeFields[field.Key]; ok {
+		return field, false
+	}
+	if len(l.includeFields) == 0 {
+		return field, true
+	}
+	_, ok := l.includeFields[field.Key]
+	return field, ok
+}
+
+// keySet 把键的列表转换为集合, 列表为空时返回 nil
+func keySet(keys []string) map[string]struct{} {
+	if len(keys) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+	return set
+}
+
+// redactField 使用自定义脱敏函数处理字段
+func (l *Logger) redactField(field zapcore.Field) (zapcore.Field, bool) {
+	if field.Type == zapcore.NamespaceType || field.Type == zapcore.SkipType {
+		return field, true
+	}
+	if val, ok := l.redactFunc(field.Key, fieldValue(field)); ok {
+		return zap.Any(field.Key, val), true
+	}
+	return field, true
+}
+
+// roundFloatField 按配置的精度对浮点数字段四舍五入, 其他字段保持不变
+func (l *Logger) roundFloatField(field zapcore.Field) (zapcore.Field, bool) {
+	switch field.Type {
+	case zapcore.Float64Type:
+		val := roundFloat(math.Float64frombits(uint64(field.Integer)), l.floatPrecision)
+		field.Integer = int64(math.Float64bits(val))
+	case zapcore.Float32Type:
+		val := roundFloat(float64(math.Float32frombits(uint32(field.Integer))), l.floatPrecision)
+		field.Integer = int64(math.Float32bits(float32(val)))
+	}
+	return field, true
+}
+
+// newlineReplacer 把换行替换为转义形式
+var newlineReplacer = strings.NewReplacer("\r\n", `\r\n`, "\n", `\n`, "\r", `\r`)
+
+// sanitizeNewlinesField 替换字符串字段中的换行, 其他字段保持不变
+func sanitizeNewlinesField(field zapcore.Field) (zapcore.Field, bool) {
+	if field.Type == zapcore.StringType && strings.ContainsAny(field.String, "\r\n") {
+		field.String = newlineReplacer.Replace(field.String)
+	}
+	return field, true
+}
+
+// truncateField 截断编码后超过 maxFieldSize 的字段
+func (l *Logger) truncateField(field zapcore.Field) (zapcore.Field, bool) {
+	switch field.Type {
+	case zapcore.ReflectType, zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType:
+		if b, ok := l.encodeFieldValue(field); ok && len(b) > l.maxFieldSize {
+			return zap.String(field.Key, truncateString(b, l.maxFieldSize)), true
+		}
+	case zapcore.StringType:
+		if len(field.String) > l.maxFieldSize {
+			field.String = truncateString(field.String, l.maxFieldSize)
+		}
+	case zapcore.StringerType:
+		if str := fmt.Sprint(field.Interface); len(str) > l.maxFieldSize {
+			return zap.String(field.Key, truncateString(str, l.maxFieldSize)), true
+		}
+	case zapcore.ByteStringType:
+		if b := field.Interface.([]byte); len(b) > l.maxFieldSize {
+			return zap.String(field.Key, truncateString(b, l.maxFieldSize)), true
+		}
+	case zapcore.BinaryType:
+		// 限制的是 base64 编码后的长度, 截断到编码后不超过 maxFieldSize 的字节数
+		if b := field.Interface.([]byte); base64.StdEncoding.EncodedLen(len(b)) > l.maxFieldSize {
+			n := base64.StdEncoding.DecodedLen(l.maxFieldSize)
+			return zap.String(field.Key, base64.StdEncoding.EncodeToString(b[:n])+truncatedMarker(len(b)-n)), true
+		}
+	}
+	return field, true
+}
+
+// encodeFieldValue 把字段的值编码为 JSON, 用于计算复杂字段编码后的大小, 编码失败时 ok 为 false
+func (l *Logger) encodeFieldValue(field zapcore.Field) ([]byte, bool) {
+	var config zapcore.EncoderConfig
+	if l.maxReflectDepth > 0 {
+		config.NewReflectedEncoder = newDepthReflectedEncoder(l.maxReflectDepth)
+	}
+	field.Key = ""
+	buf, err := zapcore.NewJSONEncoder(config).EncodeEntry(zapcore.Entry{}, []zapcore.Field{field})
+	if err != nil {
+		return nil, false
+	}
+	defer buf.Free()
+
+	// 输出为 {"":<value>}\n, 编码失败时 zap 会改为输出 Error 字段
+	const prefix, suffix = `{"":`, "}\n"
+	b := buf.Bytes()
+	if !bytes.HasPrefix(b, []byte(prefix)) || !bytes.HasSuffix(b, []byte(suffix)) {
+		return nil, false
+	}
+	return append([]byte(nil), b[len(prefix):len(b)-len(suffix)]...), true
+}
+
+func (l *Logger) getLogWriter() zapcore.WriteSyncer {
+	return l.newRotateWriter(l.rotatePath)
+}
+
+// newRotateWriter 创建按轮转配置写入 path 的输出, 多个文件各自独立轮转
+func (l *Logger) newRotateWriter(path string) zapcore.WriteSyncer {
+	logWriter := &lumberjack.Logger{
+		Filename:   path,             // 日志文件的位置
+		MaxSize:    l.rotateSize,     // 在进行切割之前, 日志文件的最大大小（以MB为单位）
+		MaxBackups: l.rotateBackups,  // 保留旧文件的最大个数
+		MaxAge:     l.rotateAge,      // 保留旧文件的最大天数
+		Compress:   l.rotateCompress, // 是否压缩/归档旧文件
+	}
+	l.addCloser(logWriter.Close)
+	l.rotators = append(l.rotators, logWriter)
+	return zapcore.AddSync(logWriter)
+}
+
+// timeEncoder 按配置的格式和时区编码时间
+func (l *Logger) timeEncoder() zapcore.TimeEncoder {
+	layout := TimeLayout
+	if l.timeRFC3339Z {
+		layout = TimeLayoutRFC3339Z
+	}
+	precision := l.timePrecision
+	if precision > 0 {
+		layout = layoutWithFraction(layout, fractionDigits(precision))
+	}
+	utc := l.timeUTC
+
+	return func(t time.Time, pae zapcore.PrimitiveArrayEncoder) {
+		if utc {
+			t = t.UTC()
+		}
+		if precision > 0 {
+			t = t.Truncate(precision)
+		}
+		pae.AppendString(t.Format(layout))
+	}
+}
+
+// fractionDigits 返回表示该精度需要的小数位数, 不足1秒的精度按毫秒、微秒、纳秒取3、6、9位
+func fractionDigits(precision time.Duration) int {
+	switch {
+	case precision >= time.Second:
+		return 0
+	case precision >= time.Millisecond:
+		return 3
+	case precision >= time.Microsecond:
+		return 6
+	default:
+		return 9
+	}
+}
+
+// layoutWithFraction 把时间格式中秒之后的小数部分替换为 digits 位, digits 为 0 时去掉小数部分
+// 格式中没有小数部分时保持不变
+func layoutWithFraction(layout string, digits int) string {
+	i := strings.Index(layout, "05.")
+	if i < 0 {
+		return layout
+	}
+	start := i + len("05")
+	end := start + 1
+	for end < len(layout) && (layout[end] == '0' || layout[end] == '9') {
+		end++
+	}
+	if end == start+1 {
+		return layout
+	}
+
+	fraction := ""
+	if digits > 0 {
+		fraction = "." + strings.Repeat("0", digits)
+	}
+	return layout[:start] + fraction + layout[end:]
+}
+
+// readBuildInfo 只读取一次构建信息
+var readBuildInfo = sync.OnceValues(debug.ReadBuildInfo)
+
+// kubernetesEnvFields 从环境变量读取 Kubernetes 相关的基础字段, 未设置的环境变量不输出
+func (l *Logger) kubernetesEnvFields() []zap.Field {
+	envs := []struct{ key, env string }{
+		{"pod", cmp.Or(l.podNameEnv, PodNameEnv)},
+		{"namespace", cmp.Or(l.podNamespaceEnv, PodNamespaceEnv)},
+		{"node", cmp.Or(l.nodeNameEnv, NodeNameEnv)},
+	}
+
+	var fields []zap.Field
+	for _, e := range envs {
+		if val := os.Getenv(e.env); val != "" {
+			fields = append(fields, zap.String(e.key, val))
+		}
+	}
+	return fields
+}
+
+// buildInfoFields 返回构建信息中的版本控制字段, 构建信息不可用时返回空
+func buildInfoFields() []zap.Field {
+	info, ok := readBuildInfo()
+	if !ok {
+		return nil
+	}
+
+	var fields []zap.Field
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			fields = append(fields, zap.String("vcs_revision", setting.Value))
+		case "vcs.time":
+			fields = append(fields, zap.String("vcs_time", setting.Value))
+		}
+	}
+	return fields
+}
+
+// fieldsPool 复用追加错误字段时的切片, 写入完成后切片会被回收, core 不能在 Write 返回后继续持有字段
+var fieldsPool = sync.Pool{
+	New: func() interface{} {
+		fields := make([]zap.Field, 0, 8)
+		return &fields
+	},
+}
+
+// errorFields 把字段复制到池中的切片后追加错误字段, 不修改调用方传入的切片
+func errorFields(fields []zap.Field, err error) *[]zap.Field {
+	buf := fieldsPool.Get().(*[]zap.Field)
+	*buf = append(append((*buf)[:0], fields...), zap.Error(err))
+	return buf
+}
+
+// putFields 清空切片中的引用后放回池中
+func putFields(buf *[]zap.Field) {
+	clear(*buf)
+	*buf = (*buf)[:0]
+	fieldsPool.Put(buf)
+}
+
+// trimCallerEncoder 返回去掉文件路径前缀的调用位置编码器
+func trimCallerEncoder(prefix string) zapcore.CallerEncoder {
+	return func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+		if !caller.Defined {
+			enc.AppendString("undefined")
+			return
+		}
+		file := strings.TrimPrefix(strings.TrimPrefix(caller.File, prefix), "/")
+		enc.AppendString(file + ":" + strconv.Itoa(caller.Line))
+	}
+}
+
+// truncateString 把字符串截断到不超过 size 字节并添加截断标记, 不会截断在多字节字符中间
+func truncateString[T string | []byte](s T, size int) string {
+	if len(s) <= size {
+		return string(s)
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return string(s[:cut]) + truncatedMarker(len(s)-cut)
+}
+
+// truncatedMarker 截断标记, 记录被截掉的字节数
+func truncatedMarker(n int) string {
+	return "...[truncated " + strconv.Itoa(n) + " bytes]"
+}
+
+// roundFloat 把浮点数保留 precision 位小数, NaN 和无穷大保持不变
+func roundFloat(val float64, precision int) float64 {
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return val
+	}
+	pow := math.Pow10(precision)
+	return math.Round(val*pow) / pow
 }
 
 func checkFile(path string) error {
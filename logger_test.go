@@ -0,0 +1,267 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newTestLogger 创建只写入内存缓冲的日志实例, 标准输出只保留 Fatal, 避免测试输出过多
+func newTestLogger(t *testing.T, opts ...Option) (*Logger, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	l, err := New(append([]Option{WithConsoleLevel(zapcore.FatalLevel), WithWriter(&buf)}, opts...)...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return l, &buf
+}
+
+// parseTestEntries 解析测试日志实例输出的所有日志
+func parseTestEntries(t *testing.T, buf *bytes.Buffer) []Entry {
+	t.Helper()
+	entries, err := ParseEntries(buf)
+	if err != nil {
+		t.Fatalf("ParseEntries() error = %v", err)
+	}
+	return entries
+}
+
+func TestErrorDoesNotModifyFields(t *testing.T) {
+	l, _ := newTestLogger(t)
+
+	tests := []struct {
+		name string
+		log  func(fields ...zap.Field)
+	}{
+		{name: "Error", log: func(fields ...zap.Field) { l.Error("failed", errors.New("boom"), fields...) }},
+		{name: "ErrorCtx", log: func(fields ...zap.Field) { l.ErrorCtx(context.Background(), "failed", errors.New("boom"), fields...) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 预留容量, 直接 append 时错误字段会写入调用方的底层数组
+			fields := make([]zap.Field, 1, 4)
+			fields[0] = zap.String("key", "value")
+
+			tt.log(fields...)
+
+			if len(fields) != 1 || fields[0].Key != "key" || fields[0].String != "value" {
+				t.Errorf("fields = %v, want unchanged", fields)
+			}
+			if extra := fields[:cap(fields)][1]; extra.Key != "" || extra.Type != zapcore.UnknownType {
+				t.Errorf("spare capacity was written: %v", extra)
+			}
+		})
+	}
+}
+
+// newBenchLogger 使用预设创建日志实例, 创建期间把标准输出替换为 /dev/null, 避免输出影响结果
+func newBenchLogger(b *testing.B, preset func() (*Logger, error)) *Logger {
+	b.Helper()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatalf("open %s error = %v", os.DevNull, err)
+	}
+	b.Cleanup(func() { _ = devNull.Close() })
+
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	l, err := preset()
+	if err != nil {
+		b.Fatalf("preset error = %v", err)
+	}
+	return l
+}
+
+// BenchmarkPresets 比较 NewProduction 与 NewProductionMinimal 每条日志的开销
+// NewProductionMinimal 开启了采样, 重复的日志大部分被丢弃, 结果包含采样带来的收益
+func BenchmarkPresets(b *testing.B) {
+	presets := []struct {
+		name   string
+		preset func() (*Logger, error)
+	}{
+		{name: "NewProduction", preset: NewProduction},
+		{name: "NewProductionMinimal", preset: NewProductionMinimal},
+	}
+	err := errors.New("boom")
+	for _, p := range presets {
+		b.Run(p.name+"/Info", func(b *testing.B) {
+			l := newBenchLogger(b, p.preset)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				l.Info("request handled", zap.String("path", "/api/v1/users"), zap.Int("status", 200))
+			}
+		})
+		b.Run(p.name+"/Error", func(b *testing.B) {
+			l := newBenchLogger(b, p.preset)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				l.Error("request failed", err, zap.String("path", "/api/v1/users"))
+			}
+		})
+	}
+}
+
+// BenchmarkDerive 衡量 With、WithContext 和 InfoCtx 派生日志实例的开销, 派生的实例只应复制少量的状态
+func BenchmarkDerive(b *testing.B) {
+	l, err := New(WithConsoleLevel(zapcore.FatalLevel), WithWriter(io.Discard))
+	if err != nil {
+		b.Fatalf("New() error = %v", err)
+	}
+	ctx := context.WithValue(context.Background(), RequestKey, "req-1")
+
+	b.Run("With", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.With(zap.String("path", "/api/v1/users")).Info("request handled")
+		}
+	})
+	b.Run("WithContext", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.WithContext(ctx).Info("request handled")
+		}
+	})
+	b.Run("InfoCtx", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.InfoCtx(ctx, "request handled", zap.String("path", "/api/v1/users"))
+		}
+	})
+}
+
+func TestComponentReplacesComponent(t *testing.T) {
+	l, buf := newTestLogger(t, WithComponent("auth"))
+
+	l.Info("base")
+	l.Component("billing").With(zap.String("k", "v")).Once("once").Component("invoice").Info("child")
+
+	if n := strings.Count(buf.String(), `"component"`); n != 2 {
+		t.Fatalf("component appears %d times, want 2: %s", n, buf.String())
+	}
+	entries := parseTestEntries(t, buf)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	for i, want := range []string{"auth", "invoice"} {
+		if got := entries[i].Fields["component"]; got != want {
+			t.Errorf("entry %d component = %v, want %s", i, got, want)
+		}
+	}
+}
+
+func TestMaxFieldSize(t *testing.T) {
+	type payload struct {
+		Items []string `json:"items"`
+	}
+	long := strings.Repeat("x", 100)
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		field zap.Field
+		want  string
+	}{
+		{name: "string", field: zap.String("f", long), want: strings.Repeat("x", 16)},
+		{name: "strings", field: zap.Strings("f", []string{long}), want: `["` + strings.Repeat("x", 14)},
+		{name: "any", field: zap.Any("f", payload{Items: []string{long}}), want: `{"items":["xxxxx`},
+		{name: "binary", field: zap.Binary("f", []byte(long)), want: "eHh4eHh4eHh4eHh4"},
+		{name: "hex binary", opts: []Option{WithHexBinary(true)}, field: zap.Binary("f", []byte(long)), want: strings.Repeat("78", 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newTestLogger(t, append([]Option{WithMaxFieldSize(16)}, tt.opts...)...)
+			l.Info("hello", tt.field)
+
+			entries := parseTestEntries(t, buf)
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			got, _ := entries[0].String("f")
+			if !strings.HasPrefix(got, tt.want+"...[truncated ") {
+				t.Errorf("f = %q, want %q followed by the truncated marker", got, tt.want)
+			}
+			// 编码后的值不超过 maxFieldSize, 只多出截断标记
+			if value, _, _ := strings.Cut(got, "...[truncated "); len(value) > 16 {
+				t.Errorf("f value has %d bytes, want at most 16", len(value))
+			}
+		})
+	}
+}
+
+// failingWriter 每次写入都失败的输出目标
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestErrorOutputReceivesWriteErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "default"},
+		{name: "with hooks", opts: []Option{WithMessagePrefix("[test] "), WithDedupeFields(true)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errOut bytes.Buffer
+			l, err := New(append([]Option{
+				WithConsoleLevel(zapcore.FatalLevel),
+				WithWriter(failingWriter{}),
+				WithErrorOutput(zapcore.AddSync(&errOut)),
+			}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			l.Info("hello")
+
+			got := errOut.String()
+			if strings.Count(got, "write error:") != 1 || !strings.Contains(got, "disk full") {
+				t.Errorf("error output = %q, want a single write error containing disk full", got)
+			}
+		})
+	}
+}
+
+func TestTeeAtLevel(t *testing.T) {
+	a, bufA := newTestLogger(t, WithLevel(zapcore.InfoLevel))
+	b, bufB := newTestLogger(t, WithLevel(zapcore.WarnLevel))
+	tee := a.Tee(b)
+
+	tee.Info("info")
+	tee.AtLevel(zapcore.DebugLevel).Component("tee").Debug("debug")
+
+	for _, tt := range []struct {
+		name string
+		buf  *bytes.Buffer
+		want []string
+	}{
+		{name: "current", buf: bufA, want: []string{"info", "debug"}},
+		{name: "other", buf: bufB, want: []string{"debug"}},
+	} {
+		entries := parseTestEntries(t, tt.buf)
+		if len(entries) != len(tt.want) {
+			t.Fatalf("%s got %d entries, want %d: %s", tt.name, len(entries), len(tt.want), tt.buf.String())
+		}
+		for i, want := range tt.want {
+			if entries[i].Message != want {
+				t.Errorf("%s entry %d message = %q, want %q", tt.name, i, entries[i].Message, want)
+			}
+		}
+		if got := entries[len(entries)-1].Fields["component"]; got != "tee" {
+			t.Errorf("%s component = %v, want tee", tt.name, got)
+		}
+	}
+}
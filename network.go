@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DropPolicy 网络输出的缓冲已满时丢弃日志的策略
+type DropPolicy int
+
+const (
+	// DropOldest 丢弃缓冲中最早的日志, 优先保留最新的日志
+	DropOldest DropPolicy = iota
+	// DropNewest 丢弃新写入的日志, 优先保留断线时最早的日志
+	DropNewest
+)
+
+// Dialer 建立网络输出使用的连接, 断线后会再次调用以重连
+type Dialer func() (net.Conn, error)
+
+// networkSink 通过 WithNetworkSink 添加的网络输出
+type networkSink struct {
+	dial        Dialer
+	bufferBytes int
+	dropPolicy  DropPolicy
+}
+
+// networkStats 所有网络输出共享的统计计数
+type networkStats struct {
+	dropped    atomic.Uint64
+	reconnects atomic.Uint64
+}
+
+// NetworkDroppedTotal 返回网络输出因缓冲已满而丢弃的日志条数, 没有网络输出时返回0
+func (l *Logger) NetworkDroppedTotal() uint64 {
+	if l.networkStats == nil {
+		return 0
+	}
+	return l.networkStats.dropped.Load()
+}
+
+// NetworkReconnectsTotal 返回网络输出断线后重新连接成功的次数, 没有网络输出时返回0
+func (l *Logger) NetworkReconnectsTotal() uint64 {
+	if l.networkStats == nil {
+		return 0
+	}
+	return l.networkStats.reconnects.Load()
+}
+
+const (
+	// socketBufferBytes 连接断开期间最多缓存的日志字节数
+	socketBufferBytes = 1 << 20
+	// socketMinBackoff 首次重连前的等待时间
+	socketMinBackoff = 100 * time.Millisecond
+	// socketMaxBackoff 重连等待时间的上限
+	socketMaxBackoff = 30 * time.Second
+)
+
+// reconnectWriter 断线后自动重连的 WriteSyncer, 连接不可用时把日志暂存在内存中, 超出上限时按策略丢弃
+// 重连在 Write 中按退避间隔同步尝试, 不启动后台协程
+type reconnectWriter struct {
+	mu        sync.Mutex
+	dial      Dialer
+	conn      net.Conn
+	connected bool
+	pending   [][]byte
+	size      int
+	limit     int
+	policy    DropPolicy
+	timeout   time.Duration
+	stats     *networkStats
+	backoff   time.Duration
+	nextDial  time.Time
+}
+
+func newReconnectWriter(sink networkSink, timeout time.Duration, stats *networkStats) *reconnectWriter {
+	return &reconnectWriter{dial: sink.dial, limit: sink.bufferBytes, policy: sink.dropPolicy, timeout: timeout, stats: stats}
+}
+
+// Write 写入一条日志, 连接正常且没有暂存的日志时直接写入连接, 否则暂存, 因此总是返回成功
+func (w *reconnectWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// 先写出暂存的日志, 保证日志的顺序
+	w.flush()
+	if len(w.pending) == 0 && w.connect() && w.writeConn(p) {
+		return len(p), nil
+	}
+	// zap 会复用 p 的底层缓冲, 暂存时需要复制
+	w.buffer(append([]byte(nil), p...))
+	return len(p), nil
+}
+
+// Sync 尝试把暂存的日志写入连接
+func (w *reconnectWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.flush()
+	return nil
+}
+
+// Close 写入暂存的日志后关闭连接, 仍然无法写入的日志会被丢弃
+func (w *reconnectWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.flush()
+	w.pending, w.size = nil, 0
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// buffer 暂存一条日志, 超出上限时按策略丢弃, 单条超过上限的日志无法暂存, 直接丢弃
+func (w *reconnectWriter) buffer(p []byte) {
+	if len(p) > w.limit || (w.policy == DropNewest && w.size+len(p) > w.limit) {
+		w.stats.dropped.Add(1)
+		return
+	}
+
+	w.pending = append(w.pending, p)
+	w.size += len(p)
+	for w.size > w.limit && len(w.pending) > 0 {
+		w.size -= len(w.pending[0])
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+		w.stats.dropped.Add(1)
+	}
+}
+
+// flush 按顺序写出暂存的日志, 写入失败时停止, 剩余的日志继续暂存
+func (w *reconnectWriter) flush() {
+	for len(w.pending) > 0 {
+		if !w.connect() || !w.writeConn(w.pending[0]) {
+			return
+		}
+		w.size -= len(w.pending[0])
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+	}
+}
+
+// writeConn 把一条日志写入连接, 写入失败时断开连接并等待下次重连
+func (w *reconnectWriter) writeConn(p []byte) bool {
+	if w.timeout > 0 {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+	if _, err := w.conn.Write(p); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		w.scheduleRedial()
+		return false
+	}
+	return true
+}
+
+// connect 在没有连接且已到重连时间时建立连接, 返回当前是否有可用的连接
+func (w *reconnectWriter) connect() bool {
+	if w.conn != nil {
+		return true
+	}
+	if time.Now().Before(w.nextDial) {
+		return false
+	}
+
+	conn, err := w.dial()
+	if err != nil {
+		w.scheduleRedial()
+		return false
+	}
+	if w.connected {
+		w.stats.reconnects.Add(1)
+	}
+	w.conn = conn
+	w.connected = true
+	w.backoff = 0
+	return true
+}
+
+// scheduleRedial 按指数退避计算下次重连的时间
+func (w *reconnectWriter) scheduleRedial() {
+	switch {
+	case w.backoff == 0:
+		w.backoff = socketMinBackoff
+	case w.backoff < socketMaxBackoff:
+		w.backoff = min(w.backoff*2, socketMaxBackoff)
+	}
+	w.nextDial = time.Now().Add(w.backoff)
+}
+
+// unixSocketSink 返回写入 unix 套接字的网络输出
+func (l *Logger) unixSocketSink() networkSink {
+	return networkSink{
+		dial: func() (net.Conn, error) {
+			return net.DialTimeout("unix", l.unixSocket, l.sinkWriteTimeout)
+		},
+		bufferBytes: socketBufferBytes,
+		dropPolicy:  DropOldest,
+	}
+}
+
+// newNetworkCore 创建按行写入 JSON 日志到网络连接的 core
+func (l *Logger) newNetworkCore(encoderConfig zapcore.EncoderConfig, enab zapcore.LevelEnabler, sink networkSink) zapcore.Core {
+	if l.networkStats == nil {
+		l.networkStats = &networkStats{}
+	}
+	writer := newReconnectWriter(sink, l.sinkWriteTimeout, l.networkStats)
+	l.addCloser(writer.Close)
+
+	// 接收端按行解析, 不使用自定义的行尾
+	encoderConfig.LineEnding = zapcore.DefaultLineEnding
+	return zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), l.countWriter(writer), enab)
+}
@@ -0,0 +1,25 @@
+// Package protolog 提供以 protojson 格式记录 protobuf 消息的字段, 单独成包以免主包依赖 protobuf
+package protolog
+
+import (
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtoField 以 protojson 格式记录 protobuf 消息, 只有在日志真正输出时才会序列化
+func ProtoField(key string, m proto.Message) zap.Field {
+	return zap.Reflect(key, protoMessage{m: m})
+}
+
+// protoMessage 延迟使用 protojson 序列化的 protobuf 消息
+type protoMessage struct {
+	m proto.Message
+}
+
+func (p protoMessage) MarshalJSON() ([]byte, error) {
+	if p.m == nil {
+		return []byte("null"), nil
+	}
+	return protojson.Marshal(p.m)
+}
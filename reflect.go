@@ -0,0 +1,313 @@
+package logger
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// maxDepthMarker 超过最大深度的值替换为该标记
+	maxDepthMarker = "[max depth exceeded]"
+	// cycleMarker 循环引用的值替换为该标记
+	cycleMarker = "[cycle]"
+	// defaultMaxReflectDepth 反射编码和 StructFields 默认允许的最大嵌套深度
+	defaultMaxReflectDepth = 5
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// depthReflectedEncoder 限制嵌套深度的 zap.Any 编码器, 没有超过深度且没有循环引用时与 zap 默认的编码结果一致
+type depthReflectedEncoder struct {
+	enc      *json.Encoder
+	maxDepth int
+}
+
+// newDepthReflectedEncoder 返回 zapcore.EncoderConfig.NewReflectedEncoder 使用的构造函数
+func newDepthReflectedEncoder(maxDepth int) func(io.Writer) zapcore.ReflectedEncoder {
+	return func(w io.Writer) zapcore.ReflectedEncoder {
+		enc := json.NewEncoder(w)
+		// 与 zap 默认的编码器保持一致
+		enc.SetEscapeHTML(false)
+		return &depthReflectedEncoder{enc: enc, maxDepth: maxDepth}
+	}
+}
+
+func (e *depthReflectedEncoder) Encode(v interface{}) error {
+	w := depthWalker{maxDepth: e.maxDepth}
+	rv := reflect.ValueOf(v)
+	if !w.exceeds(rv, 1) {
+		return e.enc.Encode(v)
+	}
+	return e.enc.Encode(w.limit(rv, 1))
+}
+
+// depthWalker 按 encoding/json 的规则遍历值, 检查并截断超过深度的部分
+type depthWalker struct {
+	maxDepth int
+	// visiting 当前路径上的指针, 用于发现循环引用
+	visiting map[uintptr]bool
+}
+
+// exceeds 检查值是否超过最大深度或包含循环引用, 不分配新的值
+func (w *depthWalker) exceeds(rv reflect.Value, depth int) bool {
+	if !rv.IsValid() || selfMarshaler(rv.Type()) {
+		return false
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return false
+		}
+		if !w.enter(rv.Pointer()) {
+			return true
+		}
+		defer w.leave(rv.Pointer())
+		return w.exceeds(rv.Elem(), depth)
+	case reflect.Interface:
+		return !rv.IsNil() && w.exceeds(rv.Elem(), depth)
+	case reflect.Struct:
+		if depth > w.maxDepth {
+			return true
+		}
+		exceeded := false
+		forEachJSONField(rv, func(_ string, fv reflect.Value) bool {
+			exceeded = w.exceeds(fv, depth+1)
+			return !exceeded
+		})
+		return exceeded
+	case reflect.Map:
+		if rv.IsNil() || rv.Len() == 0 {
+			return false
+		}
+		if depth > w.maxDepth {
+			return true
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			if w.exceeds(iter.Value(), depth+1) {
+				return true
+			}
+		}
+		return false
+	case reflect.Slice, reflect.Array:
+		if isBytes(rv) || rv.Len() == 0 {
+			return false
+		}
+		if depth > w.maxDepth {
+			return true
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if w.exceeds(rv.Index(i), depth+1) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// limit 返回截断后的值, 超过深度的部分替换为 maxDepthMarker, 循环引用替换为 cycleMarker
+func (w *depthWalker) limit(rv reflect.Value, depth int) interface{} {
+	if !rv.IsValid() {
+		return nil
+	}
+	if selfMarshaler(rv.Type()) {
+		return rv.Interface()
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+		if !w.enter(rv.Pointer()) {
+			return cycleMarker
+		}
+		defer w.leave(rv.Pointer())
+		return w.limit(rv.Elem(), depth)
+	case reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return w.limit(rv.Elem(), depth)
+	case reflect.Struct:
+		if depth > w.maxDepth {
+			return maxDepthMarker
+		}
+		var obj orderedObject
+		forEachJSONField(rv, func(name string, fv reflect.Value) bool {
+			obj = append(obj, orderedField{key: name, value: w.limit(fv, depth+1)})
+			return true
+		})
+		return obj
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		if depth > w.maxDepth {
+			return maxDepthMarker
+		}
+		// 键的格式由 encoding/json 处理, 这里只替换值
+		m := reflect.MakeMapWithSize(reflect.MapOf(rv.Type().Key(), reflect.TypeOf((*interface{})(nil)).Elem()), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			val := reflect.ValueOf(w.limit(iter.Value(), depth+1))
+			if !val.IsValid() {
+				val = reflect.Zero(m.Type().Elem())
+			}
+			m.SetMapIndex(iter.Key(), val)
+		}
+		return m.Interface()
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		if isBytes(rv) {
+			return rv.Interface()
+		}
+		if depth > w.maxDepth {
+			return maxDepthMarker
+		}
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = w.limit(rv.Index(i), depth+1)
+		}
+		return items
+	default:
+		return rv.Interface()
+	}
+}
+
+// enter 记录进入一个指针, 该指针已经在当前路径上时返回 false
+func (w *depthWalker) enter(ptr uintptr) bool {
+	if w.visiting == nil {
+		w.visiting = make(map[uintptr]bool)
+	}
+	if w.visiting[ptr] {
+		return false
+	}
+	w.visiting[ptr] = true
+	return true
+}
+
+func (w *depthWalker) leave(ptr uintptr) {
+	delete(w.visiting, ptr)
+}
+
+// selfMarshaler 类型自己实现了 JSON 或文本编码, 例如 time.Time, 交给 encoding/json 处理
+func selfMarshaler(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		(t.Kind() != reflect.Pointer && (reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)))
+}
+
+// isBytes 判断是否为 []byte, encoding/json 把它编码为 base64 字符串
+func isBytes(rv reflect.Value) bool {
+	return rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8
+}
+
+// forEachJSONField 按 encoding/json 的规则遍历结构体字段: 使用 json 标签的名称, 跳过 "-"、未导出字段和 omitempty 的空值,
+// 没有标签的嵌入结构体展开到外层; fn 返回 false 时停止遍历
+func forEachJSONField(rv reflect.Value, fn func(name string, fv reflect.Value) bool) bool {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := rv.Field(i)
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if fv.Kind() == reflect.Pointer {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				if !forEachJSONField(fv, fn) {
+					return false
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if hasTagOption(opts, "omitempty") && isEmptyJSONValue(fv) {
+			continue
+		}
+		if !fn(name, fv) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmptyJSONValue 与 encoding/json 的 omitempty 判断一致, 结构体永远不为空
+func isEmptyJSONValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return rv.IsZero()
+	default:
+		return false
+	}
+}
+
+// orderedField 截断后结构体的一个字段
+type orderedField struct {
+	key   string
+	value interface{}
+}
+
+// orderedObject 按字段顺序编码的 JSON 对象, 保持与结构体相同的字段顺序
+type orderedObject []orderedField
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		var val bytes.Buffer
+		enc := json.NewEncoder(&val)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(field.value); err != nil {
+			return nil, err
+		}
+		buf.Write(bytes.TrimRight(val.Bytes(), "\n"))
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
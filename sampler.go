@@ -0,0 +1,252 @@
+package logger
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// samplerLevels 采样计数覆盖的级别数量, 从 Debug 到 Fatal
+	samplerLevels = int(zapcore.FatalLevel-zapcore.DebugLevel) + 1
+	// samplerBuckets 每个级别的计数桶数量, 不同的 key 散列到固定数量的桶中, 因此内存占用是固定的
+	samplerBuckets = 4096
+)
+
+// samplingStats 采样的统计计数, 由同一日志实例派生出的所有实例共享
+type samplingStats struct {
+	// sampled 通过采样被保留的日志条数
+	sampled atomic.Uint64
+	// dropped 被采样丢弃的日志条数
+	dropped atomic.Uint64
+}
+
+// samplerFuncCore 按用户提供的函数对每条日志做采样决策的 core
+type samplerFuncCore struct {
+	zapcore.Core
+	decide  func(ent zapcore.Entry) zapcore.SamplingDecision
+	exclude func(ent zapcore.Entry) bool
+	stats   *samplingStats
+}
+
+func (c *samplerFuncCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplerFuncCore{Core: c.Core.With(fields), decide: c.decide, exclude: c.exclude, stats: c.stats}
+}
+
+func (c *samplerFuncCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if excludedFromSampling(c.exclude, ent) {
+		return c.Core.Check(ent, ce)
+	}
+	if c.decide(ent)&zapcore.LogDropped != 0 {
+		c.stats.dropped.Add(1)
+		return ce
+	}
+	c.stats.sampled.Add(1)
+	return c.Core.Check(ent, ce)
+}
+
+// SampledTotal 返回通过采样被保留的日志条数, 未开启采样时返回0
+func (l *Logger) SampledTotal() uint64 {
+	if l.samplingStats == nil {
+		return 0
+	}
+	return l.samplingStats.sampled.Load()
+}
+
+// DroppedTotal 返回被采样丢弃的日志条数, 未开启采样时返回0
+func (l *Logger) DroppedTotal() uint64 {
+	if l.samplingStats == nil {
+		return 0
+	}
+	return l.samplingStats.dropped.Load()
+}
+
+// samplerCounter 一个计数桶, 每个周期开始时清零
+type samplerCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// incCheckReset 计数加一并返回当前周期内的计数, 进入新周期时先清零
+func (c *samplerCounter) incCheckReset(t time.Time, tick time.Duration) uint64 {
+	tn := t.UnixNano()
+	resetAfter := c.resetAt.Load()
+	if resetAfter > tn {
+		return c.count.Add(1)
+	}
+
+	c.count.Store(1)
+
+	newResetAfter := tn + tick.Nanoseconds()
+	if !c.resetAt.CompareAndSwap(resetAfter, newResetAfter) {
+		// 其他协程已经进入了新周期
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+// countSampler 与 zap 的采样规则相同: 每个周期内同一级别同一 key 的前 initial 条全部输出, 之后每 thereafter 条输出一条
+// 计数使用固定数量的桶, 内存占用约为 samplerLevels*samplerBuckets*16 字节, 不随 key 的数量增长, 散列冲突的 key 会共享计数
+type countSampler struct {
+	initial    uint64
+	thereafter uint64
+	tick       time.Duration
+	counters   [samplerLevels][samplerBuckets]samplerCounter
+}
+
+func newCountSampler(initial, thereafter int, tick time.Duration) *countSampler {
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return &countSampler{
+		initial:    uint64(initial),
+		thereafter: uint64(thereafter),
+		tick:       tick,
+	}
+}
+
+// sample 返回该条日志是否应当输出
+func (s *countSampler) sample(lvl zapcore.Level, key string, t time.Time) bool {
+	if lvl < zapcore.DebugLevel || lvl > zapcore.FatalLevel {
+		return true
+	}
+
+	counter := &s.counters[lvl-zapcore.DebugLevel][fnv32a(key)%samplerBuckets]
+
+	n := counter.incCheckReset(t, s.tick)
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
+
+// reset 清空所有计数
+func (s *countSampler) reset() {
+	for i := range s.counters {
+		for j := range s.counters[i] {
+			s.counters[i][j].count.Store(0)
+			s.counters[i][j].resetAt.Store(0)
+		}
+	}
+}
+
+// fnv32a 计算字符串的 FNV-1a 散列, 避免 hash/fnv 在每条日志上的内存分配
+func fnv32a(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint32(s[i])
+		hash *= prime32
+	}
+	return hash
+}
+
+// samplerCore 按级别和消息内容计数采样的 core
+type samplerCore struct {
+	zapcore.Core
+	sampler *countSampler
+	exclude func(ent zapcore.Entry) bool
+	stats   *samplingStats
+}
+
+func (c *samplerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplerCore{Core: c.Core.With(fields), sampler: c.sampler, exclude: c.exclude, stats: c.stats}
+}
+
+func (c *samplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if excludedFromSampling(c.exclude, ent) {
+		return c.Core.Check(ent, ce)
+	}
+	if !c.sampler.sample(ent.Level, ent.Message, ent.Time) {
+		c.stats.dropped.Add(1)
+		return ce
+	}
+	c.stats.sampled.Add(1)
+	return c.Core.Check(ent, ce)
+}
+
+// keySamplerCore 按指定字段的值计数采样的 core, 字段值相同的日志共享采样计数
+// 字段可以来自 With 也可以来自单条日志, 单条日志上的值优先, 没有该字段的日志不参与采样
+type keySamplerCore struct {
+	zapcore.Core
+	key      string
+	value    string
+	hasValue bool
+	sampler  *countSampler
+	exclude  func(ent zapcore.Entry) bool
+	stats    *samplingStats
+}
+
+func (c *keySamplerCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	if value, ok := samplingKeyValue(c.key, fields); ok {
+		clone.value, clone.hasValue = value, true
+	}
+	return &clone
+}
+
+func (c *keySamplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *keySamplerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if excludedFromSampling(c.exclude, ent) {
+		return writeThrough(c.Core, ent, fields)
+	}
+	value, ok := samplingKeyValue(c.key, fields)
+	if !ok {
+		value, ok = c.value, c.hasValue
+	}
+	if ok {
+		if !c.sampler.sample(ent.Level, value, ent.Time) {
+			c.stats.dropped.Add(1)
+			return nil
+		}
+		c.stats.sampled.Add(1)
+	}
+	return writeThrough(c.Core, ent, fields)
+}
+
+// samplingKeyValue 查找指定字段并返回其值的字符串形式, 多次出现时取最后一个
+func samplingKeyValue(key string, fields []zapcore.Field) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key != key {
+			continue
+		}
+		if fields[i].Type == zapcore.StringType {
+			return fields[i].String, true
+		}
+		return fmt.Sprint(fieldValue(fields[i])), true
+	}
+	return "", false
+}
+
+// excludedFromSampling 日志是否匹配 WithSamplingExclude, 匹配的日志不经过采样
+func excludedFromSampling(exclude func(ent zapcore.Entry) bool, ent zapcore.Entry) bool {
+	return exclude != nil && exclude(ent)
+}
+
+// ResetSampler 清空采样计数, 未开启 WithSampling 或 WithSamplingByKey 时不做任何事情
+func (l *Logger) ResetSampler() {
+	if l.sampler != nil {
+		l.sampler.reset()
+	}
+	if l.keySampler != nil {
+		l.keySampler.reset()
+	}
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sourceLocationKey Cloud Logging 识别调用位置的字段名
+const sourceLocationKey = "logging.googleapis.com/sourceLocation"
+
+// stackdriverLevelEncoder 把 zap 的级别映射到 Cloud Logging 的 severity
+func stackdriverLevelEncoder(lvl zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	switch lvl {
+	case zapcore.DebugLevel:
+		enc.AppendString("DEBUG")
+	case zapcore.InfoLevel:
+		enc.AppendString("INFO")
+	case zapcore.WarnLevel:
+		enc.AppendString("WARNING")
+	case zapcore.ErrorLevel:
+		enc.AppendString("ERROR")
+	case zapcore.DPanicLevel:
+		enc.AppendString("CRITICAL")
+	case zapcore.PanicLevel:
+		enc.AppendString("ALERT")
+	case zapcore.FatalLevel:
+		enc.AppendString("EMERGENCY")
+	default:
+		enc.AppendString("DEFAULT")
+	}
+}
+
+// sourceLocation Cloud Logging 的调用位置结构
+type sourceLocation zapcore.EntryCaller
+
+func (s sourceLocation) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file", s.File)
+	enc.AddString("line", strconv.Itoa(s.Line))
+	enc.AddString("function", s.Function)
+	return nil
+}
+
+// stackdriverHook 根据调用位置添加 sourceLocation 字段
+func stackdriverHook(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+	if !ent.Caller.Defined {
+		return ent, fields, true
+	}
+	return ent, appendFields(fields, zap.Object(sourceLocationKey, sourceLocation(ent.Caller))), true
+}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Stats 各级别实际写出的日志条数, 被级别过滤、采样、Once 丢弃的日志不计入, WithCollapseRepeats 合并的日志计为一条
+type Stats struct {
+	Debug  uint64 `json:"debug"`
+	Info   uint64 `json:"info"`
+	Warn   uint64 `json:"warn"`
+	Error  uint64 `json:"error"`
+	DPanic uint64 `json:"dpanic"`
+	Panic  uint64 `json:"panic"`
+	Fatal  uint64 `json:"fatal"`
+}
+
+// levelStats 按级别统计日志条数, 由同一日志实例派生出的所有实例共享
+type levelStats struct {
+	counts [samplerLevels]atomic.Uint64
+}
+
+// count 作为 entryHook 在每条日志写出前调用, 不修改日志
+func (s *levelStats) count(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+	if i := int(ent.Level - zapcore.DebugLevel); i >= 0 && i < samplerLevels {
+		s.counts[i].Add(1)
+	}
+	return ent, fields, true
+}
+
+func (s *levelStats) load(lvl zapcore.Level) uint64 {
+	return s.counts[lvl-zapcore.DebugLevel].Load()
+}
+
+// Stats 返回上次 ResetStats 以来各级别的日志条数, 可以通过调试接口查看错误日志是否突增
+// 各级别分别读取, 并发写入时不同级别的计数不保证属于同一时刻
+func (l *Logger) Stats() Stats {
+	if l.levelStats == nil {
+		return Stats{}
+	}
+	s := l.levelStats
+	return Stats{
+		Debug:  s.load(zapcore.DebugLevel),
+		Info:   s.load(zapcore.InfoLevel),
+		Warn:   s.load(zapcore.WarnLevel),
+		Error:  s.load(zapcore.ErrorLevel),
+		DPanic: s.load(zapcore.DPanicLevel),
+		Panic:  s.load(zapcore.PanicLevel),
+		Fatal:  s.load(zapcore.FatalLevel),
+	}
+}
+
+// ResetStats 把各级别的日志条数清零, 同一日志实例派生出的所有实例共享计数
+func (l *Logger) ResetStats() {
+	if l.levelStats == nil {
+		return
+	}
+	for i := range l.levelStats.counts {
+		l.levelStats.counts[i].Store(0)
+	}
+}